@@ -0,0 +1,171 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// taler-go-config inspects and modifies Taler configuration files
+// like taler-config does.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/schanzen/taler-go/pkg/config"
+)
+
+var (
+	cfgFile      = flag.String("c", "", "configuration file to use (default: $XDG_CONFIG_HOME/taler.conf)")
+	defaultsDir  = flag.String("defaults", os.Getenv("TALER_CONFIG_DEFAULTS"), "directory with the default configuration files (*.conf)")
+	section      = flag.String("s", "", "name of the section to access")
+	option       = flag.String("o", "", "name of the option to access")
+	value        = flag.String("V", "", "value to set for the option (requires -s and -o)")
+	filename     = flag.Bool("f", false, "expand the option value as filename")
+	listSections = flag.Bool("S", false, "list the available sections")
+	diagnostics  = flag.Bool("d", false, "dump the effective configuration with the file and line setting each option")
+	full         = flag.Bool("F", false, "dump the full effective configuration")
+	rewrite      = flag.Bool("w", false, "rewrite the configuration file, dropping options equal to the defaults")
+)
+
+func defaultConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	f := filepath.Join(dir, "taler.conf")
+	if _, err := os.Stat(f); err != nil {
+		return ""
+	}
+	return f
+}
+
+func fail(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
+
+func dump(cfg *config.Config, withProvenance bool) {
+	for i, s := range cfg.Sections() {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("[%s]\n", s)
+		for _, o := range cfg.Options(s) {
+			v, _ := cfg.GetString(s, o)
+			if withProvenance {
+				file, line, _ := cfg.Provenance(s, o)
+				if file == "" {
+					fmt.Println("# set on the command line")
+				} else {
+					fmt.Printf("# %s:%d\n", file, line)
+				}
+			}
+			fmt.Printf("%s = %s\n", o, v)
+		}
+	}
+}
+
+// Sets the option and rewrites cfgFile. Options from default and
+// inlined files are not written to cfgFile.
+func setOption(cfg *config.Config, cfgFile string, section string, option string, value string) error {
+	cfg.Set(section, option, value)
+	return cfg.WriteDiffFile(cfgFile)
+}
+
+func main() {
+	flag.Parse()
+	if *cfgFile == "" {
+		*cfgFile = defaultConfigFile()
+	}
+	cfg, err := config.Load(*defaultsDir, *cfgFile)
+	if err != nil {
+		fail("Failed to load configuration: %v", err)
+	}
+
+	setValue := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "V" {
+			setValue = true
+		}
+	})
+	switch {
+	case *listSections:
+		for _, s := range cfg.Sections() {
+			fmt.Println(s)
+		}
+	case *diagnostics:
+		dump(cfg, true)
+	case *full:
+		dump(cfg, false)
+	case setValue:
+		if *section == "" || *option == "" {
+			fail("-V requires -s and -o")
+		}
+		if *cfgFile == "" {
+			fail("-V requires a configuration file (-c)")
+		}
+		err := setOption(cfg, *cfgFile, *section, *option, *value)
+		if err != nil {
+			fail("Failed to write %s: %v", *cfgFile, err)
+		}
+	case *rewrite:
+		if *cfgFile == "" {
+			fail("-w requires a configuration file (-c)")
+		}
+		err := cfg.WriteDiffFile(*cfgFile)
+		if err != nil {
+			fail("Failed to write %s: %v", *cfgFile, err)
+		}
+	case *section != "" && *option != "":
+		var v string
+		if *filename {
+			v, err = cfg.GetFilename(*section, *option)
+		} else {
+			v, err = cfg.GetString(*section, *option)
+		}
+		if err != nil {
+			fail("%v", err)
+		}
+		fmt.Println(v)
+	case *section != "":
+		options := cfg.Options(*section)
+		if options == nil {
+			fail("Section [%s] not found", *section)
+		}
+		for _, o := range options {
+			if *filename {
+				v, err := cfg.GetFilename(*section, o)
+				if err != nil {
+					fail("%v", err)
+				}
+				fmt.Printf("%s = %s\n", o, v)
+				continue
+			}
+			v, _ := cfg.GetString(*section, o)
+			fmt.Printf("%s = %s\n", o, v)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Nothing to do. Use -S to list sections or -s and -o to access options.")
+		flag.Usage()
+		os.Exit(1)
+	}
+}
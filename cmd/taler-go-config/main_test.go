@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/schanzen/taler-go/pkg/config"
+)
+
+func TestSetOption(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "taler.conf")
+	os.WriteFile(filepath.Join(dir, "secret.conf"), []byte("[merchantdb-postgres]\nPASSWORD = hunter2\n"), 0600)
+	os.WriteFile(cfgFile, []byte("# Merchant database\n@inline-secret@ merchantdb-postgres secret.conf\n\n[merchant]\nSERVE = unix\n"), 0644)
+	cfg, err := config.Load("", cfgFile)
+	if err != nil {
+		t.Fatalf("Failed loading config: %v", err)
+	}
+	err = setOption(cfg, cfgFile, "merchant", "SERVE", "tcp")
+	if err != nil {
+		t.Fatalf("Failed setting option: %v", err)
+	}
+	content, _ := os.ReadFile(cfgFile)
+	expected := "# Merchant database\n@inline-secret@ merchantdb-postgres secret.conf\n\n[merchant]\nSERVE = tcp\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected config file:\n%s", content)
+	}
+	if strings.Contains(string(content), "hunter2") {
+		t.Errorf("Secret written to %s", cfgFile)
+	}
+}
@@ -0,0 +1,146 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package sqltest provides a database/sql driver for tests that hands
+// every statement to Go functions instead of a database.
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// The result of a query
+type Rows struct {
+	// The column names
+	Columns []string
+
+	// The rows, one value per column
+	Values [][]driver.Value
+}
+
+// Emulates a database. Statements are rejected if the corresponding
+// function is nil. Transactions are accepted and do nothing.
+type DB struct {
+	// Called for statements run with Exec
+	Exec func(query string, args []driver.Value) error
+
+	// Called for statements run with Query
+	Query func(query string, args []driver.Value) (*Rows, error)
+}
+
+const driverName = "taler-go-sqltest"
+
+var (
+	register sync.Once
+	dbs      sync.Map
+	serial   atomic.Int64
+)
+
+// Opens a database handing its statements to db.
+// The database is closed when the test ends.
+func Open(t testing.TB, db *DB) *sql.DB {
+	register.Do(func() {
+		sql.Register(driverName, fakeDriver{})
+	})
+	name := t.Name() + "#" + strconv.FormatInt(serial.Add(1), 10)
+	dbs.Store(name, db)
+	sqlDB, err := sql.Open(driverName, name)
+	if err != nil {
+		t.Fatalf("Failed opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		dbs.Delete(name)
+	})
+	return sqlDB
+}
+
+type fakeDriver struct{}
+type fakeConn struct {
+	db *DB
+}
+type fakeStmt struct {
+	db    *DB
+	query string
+}
+type fakeTx struct{}
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	db, ok := dbs.Load(name)
+	if !ok {
+		return nil, errors.New("unknown test database")
+	}
+	return &fakeConn{db.(*DB)}, nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c.db, query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.db.Exec == nil {
+		return nil, errors.New("unexpected statement " + s.query)
+	}
+	if err := s.db.Exec(s.query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.db.Query == nil {
+		return nil, errors.New("unexpected query " + s.query)
+	}
+	rows, err := s.db.Query(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows.Columns, rows.Values}, nil
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
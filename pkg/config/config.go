@@ -0,0 +1,646 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+// Package config implements parsing of taler.conf style configuration
+// files with the semantics of taler-config.
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Returned (wrapped) by the getters if the option is not set
+var ErrOptionMissing = errors.New("option missing")
+
+// Maximum nesting depth of @inline@ directives
+const maxInlineDepth = 128
+
+// A single option and where it was set
+type entry struct {
+	// The option name as first written
+	name string
+
+	// The raw value
+	value string
+
+	// The file that set the value, empty if set programmatically
+	filename string
+
+	// The line in filename
+	line int
+}
+
+// A line of a parsed file, used to rewrite it
+type layoutLine struct {
+	// The line as written
+	text string
+
+	// Lower case name of the section the line is in
+	section string
+
+	// Lower case option name if the line sets an option
+	option string
+
+	// True if the line is a section header
+	header bool
+}
+
+type section struct {
+	// The section name as first written
+	name string
+
+	// Options by lower case name
+	entries map[string]*entry
+
+	// Lower case option names in insertion order
+	order []string
+}
+
+// A Taler configuration.
+// Section and option names are case-insensitive.
+type Config struct {
+	// Sections by lower case name
+	sections map[string]*section
+
+	// Lower case section names in insertion order
+	order []string
+
+	// Snapshot of the configuration after the defaults were loaded
+	defaults *Config
+
+	// The lines of all parsed files by filename
+	layouts map[string][]layoutLine
+
+	// The file last parsed with ParseFile or Parse (not a default
+	// or inlined file), written by WriteDiff
+	filename string
+}
+
+// Config can be used to load database configurations, see util.LoadDBConfig
+var _ util.ConfigSource = (*Config)(nil)
+
+// Create a new, empty configuration
+func New() *Config {
+	return &Config{
+		sections: make(map[string]*section),
+		layouts:  make(map[string][]layoutLine),
+	}
+}
+
+// Load the default configuration files from defaultsDir (if not empty)
+// and then the configuration file filename (if not empty).
+func Load(defaultsDir string, filename string) (*Config, error) {
+	c := New()
+	if defaultsDir != "" {
+		err := c.LoadDefaults(defaultsDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filename != "" {
+		err := c.ParseFile(filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Parse all *.conf files in dir in lexical order.
+// The resulting configuration is remembered as the defaults,
+// see WriteDiff.
+func (c *Config) LoadDefaults(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(files)
+	for _, f := range files {
+		err := c.ParseFile(f)
+		if err != nil {
+			return err
+		}
+	}
+	c.filename = ""
+	c.defaults = c.Clone()
+	return nil
+}
+
+// Parse a configuration file and merge it into the configuration.
+// Values set in the file override existing values.
+func (c *Config) ParseFile(filename string) error {
+	c.filename = filename
+	return c.parseFile(filename, "", 0)
+}
+
+func (c *Config) parseFile(filename string, restrictSection string, depth int) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.parse(f, filename, restrictSection, depth)
+}
+
+// Parse configuration from r and merge it into the configuration.
+// filename is recorded as the origin of the values and used to resolve
+// relative @inline@ directives.
+func (c *Config) Parse(r io.Reader, filename string) error {
+	c.filename = filename
+	return c.parse(r, filename, "", 0)
+}
+
+func (c *Config) parse(r io.Reader, filename string, restrictSection string, depth int) error {
+	if depth > maxInlineDepth {
+		return fmt.Errorf("%s: @inline@ nested too deeply", filename)
+	}
+	baseDir := filepath.Dir(filename)
+	currentSection := ""
+	var layout []layoutLine
+	defer func() {
+		if filename != "" {
+			c.layouts[filename] = layout
+		}
+	}()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		layout = append(layout, layoutLine{
+			text:    scanner.Text(),
+			section: strings.ToLower(currentSection),
+		})
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '%' {
+			continue
+		}
+		if line[0] == '@' {
+			err := c.handleDirective(line, baseDir, restrictSection, depth)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", filename, lineNo, err)
+			}
+			continue
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return fmt.Errorf("%s:%d: syntax error in section header", filename, lineNo)
+			}
+			currentSection = strings.TrimSpace(line[1 : len(line)-1])
+			layout[lineNo-1].section = strings.ToLower(currentSection)
+			layout[lineNo-1].header = true
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return fmt.Errorf("%s:%d: syntax error, expected option = value", filename, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return fmt.Errorf("%s:%d: syntax error, option name missing", filename, lineNo)
+		}
+		if currentSection == "" {
+			return fmt.Errorf("%s:%d: option %s outside of section", filename, lineNo, key)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		if restrictSection != "" && !strings.EqualFold(restrictSection, currentSection) {
+			continue
+		}
+		layout[lineNo-1].option = strings.ToLower(key)
+		c.set(currentSection, key, value, filename, lineNo)
+	}
+	return scanner.Err()
+}
+
+// Handles @inline@, @inline-matching@ and @inline-secret@
+func (c *Config) handleDirective(line string, baseDir string, restrictSection string, depth int) error {
+	directive, arg, _ := strings.Cut(line[1:], "@")
+	arg = strings.TrimSpace(arg)
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(baseDir, path)
+	}
+	switch strings.ToLower(directive) {
+	case "inline":
+		return c.parseFile(resolve(arg), restrictSection, depth+1)
+	case "inline-matching":
+		files, err := filepath.Glob(resolve(arg))
+		if err != nil {
+			return err
+		}
+		slices.Sort(files)
+		for _, f := range files {
+			err := c.parseFile(f, restrictSection, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "inline-secret":
+		secretSection, path, found := strings.Cut(arg, " ")
+		if !found {
+			return errors.New("@inline-secret@ expects a section and a filename")
+		}
+		path = resolve(strings.TrimSpace(path))
+		if _, err := os.Stat(path); err != nil {
+			util.Logger().Warn("skipping secret configuration file", "file", path, "section", secretSection, "error", err)
+			return nil
+		}
+		err := c.parseFile(path, secretSection, depth+1)
+		if errors.Is(err, os.ErrPermission) {
+			util.Logger().Warn("skipping secret configuration file", "file", path, "section", secretSection, "error", err)
+			return nil
+		}
+		return err
+	}
+	return fmt.Errorf("unknown directive @%s@", directive)
+}
+
+func (c *Config) set(sectionName string, option string, value string, filename string, line int) {
+	key := strings.ToLower(sectionName)
+	s, ok := c.sections[key]
+	if !ok {
+		s = &section{
+			name:    sectionName,
+			entries: make(map[string]*entry),
+		}
+		c.sections[key] = s
+		c.order = append(c.order, key)
+	}
+	optKey := strings.ToLower(option)
+	e, ok := s.entries[optKey]
+	if !ok {
+		e = &entry{name: option}
+		s.entries[optKey] = e
+		s.order = append(s.order, optKey)
+	}
+	e.value = value
+	e.filename = filename
+	e.line = line
+}
+
+func (c *Config) lookup(sectionName string, option string) *entry {
+	s, ok := c.sections[strings.ToLower(sectionName)]
+	if !ok {
+		return nil
+	}
+	return s.entries[strings.ToLower(option)]
+}
+
+// Set the value of an option
+func (c *Config) Set(section string, option string, value string) {
+	c.set(section, option, value, "", 0)
+}
+
+// Remove an option. Returns false if it was not set.
+func (c *Config) Remove(sectionName string, option string) bool {
+	s, ok := c.sections[strings.ToLower(sectionName)]
+	if !ok {
+		return false
+	}
+	optKey := strings.ToLower(option)
+	if _, ok := s.entries[optKey]; !ok {
+		return false
+	}
+	delete(s.entries, optKey)
+	s.order = slices.DeleteFunc(s.order, func(k string) bool { return k == optKey })
+	return true
+}
+
+// Check if the option is set
+func (c *Config) HaveValue(section string, option string) bool {
+	return c.lookup(section, option) != nil
+}
+
+// Returns the section names in the order they were first defined
+func (c *Config) Sections() []string {
+	names := make([]string, 0, len(c.order))
+	for _, key := range c.order {
+		names = append(names, c.sections[key].name)
+	}
+	return names
+}
+
+// Returns the option names of a section in the order they were first set
+func (c *Config) Options(sectionName string) []string {
+	s, ok := c.sections[strings.ToLower(sectionName)]
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(s.order))
+	for _, key := range s.order {
+		names = append(names, s.entries[key].name)
+	}
+	return names
+}
+
+// Returns the file and line that set the option.
+// filename is empty if the value was set programmatically.
+func (c *Config) Provenance(section string, option string) (filename string, line int, ok bool) {
+	e := c.lookup(section, option)
+	if e == nil {
+		return "", 0, false
+	}
+	return e.filename, e.line, true
+}
+
+// Returns a deep copy of the configuration
+func (c *Config) Clone() *Config {
+	n := New()
+	for _, key := range c.order {
+		s := c.sections[key]
+		for _, optKey := range s.order {
+			e := s.entries[optKey]
+			n.set(s.name, e.name, e.value, e.filename, e.line)
+		}
+	}
+	n.defaults = c.defaults
+	for f, layout := range c.layouts {
+		n.layouts[f] = layout
+	}
+	n.filename = c.filename
+	return n
+}
+
+// Writes the full configuration, including the options from default
+// and inlined files
+func (c *Config) Serialize(w io.Writer) error {
+	var out []string
+	for _, key := range c.order {
+		s := c.sections[key]
+		out = appendSection(out, s, s.order)
+	}
+	return writeLines(w, out)
+}
+
+// Writes the file last parsed with ParseFile or Parse with the changes
+// made with Set and Remove, see WriteDiffFile
+func (c *Config) WriteDiff(w io.Writer) error {
+	return c.writeDiff(w, c.filename)
+}
+
+// Rewrites filename with the changes made with Set and Remove, as
+// taler-config does. Comments and @inline@ directives of the file are
+// kept, options from default and inlined files are not written and
+// options equal to the defaults loaded with LoadDefaults are dropped.
+func (c *Config) WriteDiffFile(filename string) error {
+	var buf bytes.Buffer
+	err := c.writeDiff(&buf, filename)
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(filename); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(buf.Bytes())
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Check if the option has the same value in the defaults
+func (c *Config) isDefault(s *section, e *entry) bool {
+	if c.defaults == nil {
+		return false
+	}
+	de := c.defaults.lookup(s.name, e.name)
+	return de != nil && de.value == e.value
+}
+
+// Check if the option was set programmatically and must be written
+func (c *Config) isPending(s *section, e *entry) bool {
+	return e.filename == "" && !c.isDefault(s, e)
+}
+
+func (c *Config) writeDiff(w io.Writer, target string) error {
+	var out []string
+	written := make(map[*entry]bool)
+	var layout []layoutLine
+	if target != "" {
+		layout = c.layouts[target]
+	}
+	lastHeader := make(map[string]int)
+	for i, l := range layout {
+		if l.header {
+			lastHeader[l.section] = i
+		}
+	}
+	// Options set programmatically go to the end of the last block of
+	// their section
+	blockSection := ""
+	blockHeader := -1
+	blockEnd := 0
+	closeBlock := func() {
+		s := c.sections[blockSection]
+		if blockHeader < 0 || lastHeader[blockSection] != blockHeader || s == nil {
+			return
+		}
+		var lines []string
+		for _, optKey := range s.order {
+			e := s.entries[optKey]
+			if !written[e] && c.isPending(s, e) {
+				lines = append(lines, e.name+" = "+e.value)
+				written[e] = true
+			}
+		}
+		out = slices.Insert(out, blockEnd, lines...)
+	}
+	for i, l := range layout {
+		if l.header {
+			closeBlock()
+			out = append(out, l.text)
+			blockSection = l.section
+			blockHeader = i
+			blockEnd = len(out)
+			continue
+		}
+		if l.option == "" {
+			out = append(out, l.text)
+			continue
+		}
+		s := c.sections[l.section]
+		if s == nil {
+			continue
+		}
+		e := s.entries[l.option]
+		switch {
+		case e == nil || written[e]:
+			// Removed or set again later
+			continue
+		case e.filename == target && e.line == i+1:
+			if c.isDefault(s, e) {
+				continue
+			}
+			out = append(out, l.text)
+		case c.isPending(s, e):
+			out = append(out, e.name+" = "+e.value)
+		default:
+			// Overridden by another file
+			continue
+		}
+		written[e] = true
+		blockEnd = len(out)
+	}
+	closeBlock()
+	for _, key := range c.order {
+		s := c.sections[key]
+		var pending []string
+		for _, optKey := range s.order {
+			e := s.entries[optKey]
+			if !written[e] && c.isPending(s, e) {
+				pending = append(pending, optKey)
+			}
+		}
+		out = appendSection(out, s, pending)
+	}
+	return writeLines(w, out)
+}
+
+// Appends a block with the given options of s, separated by an
+// empty line from the previous block
+func appendSection(out []string, s *section, optKeys []string) []string {
+	if len(optKeys) == 0 {
+		return out
+	}
+	if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+		out = append(out, "")
+	}
+	out = append(out, "["+s.name+"]")
+	for _, optKey := range optKeys {
+		e := s.entries[optKey]
+		out = append(out, e.name+" = "+e.value)
+	}
+	return out
+}
+
+func writeLines(w io.Writer, lines []string) error {
+	bw := bufio.NewWriter(w)
+	for _, l := range lines {
+		fmt.Fprintln(bw, l)
+	}
+	return bw.Flush()
+}
+
+// Returns the raw value of an option
+func (c *Config) GetString(section string, option string) (string, error) {
+	e := c.lookup(section, option)
+	if e == nil {
+		return "", fmt.Errorf("%w: [%s]/%s", ErrOptionMissing, section, option)
+	}
+	return e.value, nil
+}
+
+// Returns the value of an option parsed as unsigned number
+func (c *Config) GetNumber(section string, option string) (uint64, error) {
+	s, err := c.GetString(section, option)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("[%s]/%s: %s is not a number", section, option, s)
+	}
+	return n, nil
+}
+
+// Returns the value of an option, which must be YES or NO
+func (c *Config) GetYesNo(section string, option string) (bool, error) {
+	s, err := c.GetChoice(section, option, []string{"YES", "NO"})
+	if err != nil {
+		return false, err
+	}
+	return s == "YES", nil
+}
+
+// Returns the value of an option, which must be one of choices
+// (compared case-insensitively). The matching choice is returned.
+func (c *Config) GetChoice(section string, option string, choices []string) (string, error) {
+	s, err := c.GetString(section, option)
+	if err != nil {
+		return "", err
+	}
+	for _, choice := range choices {
+		if strings.EqualFold(choice, s) {
+			return choice, nil
+		}
+	}
+	return "", fmt.Errorf("[%s]/%s: %s is not one of %s", section, option, s, strings.Join(choices, ", "))
+}
+
+// Returns the value of an option as amount
+func (c *Config) GetAmount(section string, option string) (*util.Amount, error) {
+	s, err := c.GetString(section, option)
+	if err != nil {
+		return nil, err
+	}
+	a, err := util.ParseAmount(s)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]/%s: %w", section, option, err)
+	}
+	return a, nil
+}
+
+// Returns the value of an option with variables expanded,
+// a leading ~ replaced by the home directory and made absolute.
+func (c *Config) GetFilename(section string, option string) (string, error) {
+	s, err := c.GetString(section, option)
+	if err != nil {
+		return "", err
+	}
+	s, err = c.ExpandDollar(s)
+	if err != nil {
+		return "", fmt.Errorf("[%s]/%s: %w", section, option, err)
+	}
+	if s == "~" || strings.HasPrefix(s, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		s = filepath.Join(home, s[1:])
+	}
+	return filepath.Abs(s)
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("Failed writing %s: %v", path, err)
+	}
+}
+
+func TestConfigParse(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "conf.d"), 0755)
+	writeFile(t, filepath.Join(dir, "conf.d", "a.conf"), "[a]\nX = 1\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "b.conf"), "[b]\nY = 2\n")
+	writeFile(t, filepath.Join(dir, "secret.conf"), "[secret]\nPASSWORD = foo\n[other]\nZ = 3\n")
+	writeFile(t, filepath.Join(dir, "inline.conf"), "[taler]\nCURRENCY = EUR\n")
+	writeFile(t, filepath.Join(dir, "test.conf"), `# Comment
+% Also a comment
+@inline@ inline.conf
+@INLINE-MATCHING@ conf.d/*.conf
+@inline-secret@ secret secret.conf
+@inline-secret@ missing missing.conf
+
+[PATHS]
+TALER_HOME = /home/taler
+DATA = ${TALER_HOME}/data
+
+[Exchange]
+BASE_URL = "https://exchange.example.com/"
+STEFAN_ABS = EUR:1.5
+IDLE = 1 h 30 min
+ENABLED = yes
+PORT = 8081
+DIR = $DATA/exchange
+FALLBACK = ${TALER_TEST_UNSET_VARIABLE:-$TALER_HOME/fallback}
+`)
+	c := New()
+	err := c.ParseFile(filepath.Join(dir, "test.conf"))
+	if err != nil {
+		t.Fatalf("Failed parsing config: %v", err)
+	}
+	s, _ := c.GetString("EXCHANGE", "base_url")
+	if s != "https://exchange.example.com/" {
+		t.Errorf("Unexpected BASE_URL %s", s)
+	}
+	s, _ = c.GetString("taler", "currency")
+	if s != "EUR" {
+		t.Errorf("@inline@ failed")
+	}
+	if !c.HaveValue("a", "x") || !c.HaveValue("b", "y") {
+		t.Errorf("@inline-matching@ failed")
+	}
+	if !c.HaveValue("secret", "password") || c.HaveValue("other", "z") {
+		t.Errorf("@inline-secret@ failed")
+	}
+	a, err := c.GetAmount("exchange", "STEFAN_ABS")
+	if err != nil || a.String() != "EUR:1.5" {
+		t.Errorf("Unexpected amount %v: %v", a, err)
+	}
+	d, err := c.GetRelativeTime("exchange", "IDLE")
+	if err != nil || d != 90*time.Minute {
+		t.Errorf("Unexpected relative time %v: %v", d, err)
+	}
+	b, err := c.GetYesNo("exchange", "ENABLED")
+	if err != nil || !b {
+		t.Errorf("Unexpected yes/no %v: %v", b, err)
+	}
+	n, err := c.GetNumber("exchange", "PORT")
+	if err != nil || n != 8081 {
+		t.Errorf("Unexpected number %d: %v", n, err)
+	}
+	f, err := c.GetFilename("exchange", "DIR")
+	if err != nil || f != "/home/taler/data/exchange" {
+		t.Errorf("Unexpected filename %s: %v", f, err)
+	}
+	f, err = c.GetFilename("exchange", "FALLBACK")
+	if err != nil || f != "/home/taler/fallback" {
+		t.Errorf("Unexpected filename %s: %v", f, err)
+	}
+	_, err = c.GetChoice("exchange", "ENABLED", []string{"maybe"})
+	if nil == err {
+		t.Errorf("Invalid choice accepted")
+	}
+	file, line, _ := c.Provenance("exchange", "PORT")
+	if file != filepath.Join(dir, "test.conf") || line != 17 {
+		t.Errorf("Unexpected provenance %s:%d", file, line)
+	}
+}
+
+func TestConfigWriteDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "defaults.conf"), "[taler]\nCURRENCY = KUDOS\nFOO = bar\n")
+	c, err := Load(dir, "")
+	if err != nil {
+		t.Fatalf("Failed loading config: %v", err)
+	}
+	c.Set("taler", "CURRENCY", "EUR")
+	c.Set("merchant", "PORT", "9966")
+	var buf bytes.Buffer
+	c.WriteDiff(&buf)
+	expected := "[taler]\nCURRENCY = EUR\n\n[merchant]\nPORT = 9966\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected diff:\n%s", buf.String())
+	}
+	buf.Reset()
+	c.Serialize(&buf)
+	if !strings.Contains(buf.String(), "FOO = bar") {
+		t.Errorf("Serialization misses defaults:\n%s", buf.String())
+	}
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	for s, expected := range map[string]time.Duration{
+		"5 s":           5 * time.Second,
+		"5s":            5 * time.Second,
+		"2 weeks 1 day": 15 * 24 * time.Hour,
+		"250 ms":        250 * time.Millisecond,
+		"forever":       Forever,
+	} {
+		d, err := ParseRelativeTime(s)
+		if err != nil || d != expected {
+			t.Errorf("Unexpected result for %s: %v (%v)", s, d, err)
+		}
+	}
+	for _, s := range []string{"", "s", "5 fortnights", "290 a 290 a"} {
+		_, err := ParseRelativeTime(s)
+		if nil == err {
+			t.Errorf("Invalid relative time %s accepted", s)
+		}
+	}
+}
+
+func TestLoadDBConfigInlineSecret(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "secret.conf"), "[merchantdb-postgres]\nCONFIG = postgres:///taler-merchant\n")
+	writeFile(t, filepath.Join(dir, "taler.conf"), "# Database\n@inline-secret@ merchantdb-postgres secret.conf\n")
+	c := New()
+	err := c.ParseFile(filepath.Join(dir, "taler.conf"))
+	if err != nil {
+		t.Fatalf("Failed parsing config: %v", err)
+	}
+	dbCfg, err := util.LoadDBConfig(c, util.PostgresSection("merchant"))
+	if err != nil {
+		t.Fatalf("Failed loading database config: %v", err)
+	}
+	name, err := dbCfg.DBName()
+	if err != nil || name != "taler-merchant" {
+		t.Errorf("Unexpected database name %s: %v", name, err)
+	}
+}
+
+func TestConfigWriteDiffFileInlineSecret(t *testing.T) {
+	dir := t.TempDir()
+	defaultsDir := filepath.Join(dir, "defaults")
+	os.Mkdir(defaultsDir, 0755)
+	writeFile(t, filepath.Join(defaultsDir, "merchant.conf"), "[merchant]\nSERVE = unix\nPORT = 9966\n")
+	writeFile(t, filepath.Join(dir, "secret.conf"), "[merchantdb-postgres]\nPASSWORD = hunter2\n")
+	writeFile(t, filepath.Join(dir, "inline.conf"), "[taler]\nCURRENCY = EUR\n")
+	cfgFile := filepath.Join(dir, "taler.conf")
+	writeFile(t, cfgFile, `# Shop configuration
+@inline@ inline.conf
+@inline-secret@ merchantdb-postgres secret.conf
+
+[merchant]
+# Listen on TCP
+SERVE = tcp
+BASE_URL = https://old.example.com/
+
+[exchange-kudos]
+MASTER_KEY = ABC
+`)
+	c, err := Load(defaultsDir, cfgFile)
+	if err != nil {
+		t.Fatalf("Failed loading config: %v", err)
+	}
+	c.Set("merchant", "BASE_URL", "https://shop.example.com/")
+	c.Set("merchant", "PORT", "8080")
+	c.Set("exchange-kudos", "CURRENCY", "KUDOS")
+	c.Remove("exchange-kudos", "MASTER_KEY")
+	c.Set("auditor", "PORT", "8083")
+	err = c.WriteDiffFile(cfgFile)
+	if err != nil {
+		t.Fatalf("Failed writing config: %v", err)
+	}
+	content, _ := os.ReadFile(cfgFile)
+	expected := `# Shop configuration
+@inline@ inline.conf
+@inline-secret@ merchantdb-postgres secret.conf
+
+[merchant]
+# Listen on TCP
+SERVE = tcp
+BASE_URL = https://shop.example.com/
+PORT = 8080
+
+[exchange-kudos]
+CURRENCY = KUDOS
+
+[auditor]
+PORT = 8083
+`
+	if string(content) != expected {
+		t.Errorf("Unexpected config file:\n%s", content)
+	}
+	c, err = Load(defaultsDir, cfgFile)
+	if err != nil {
+		t.Fatalf("Failed reloading config: %v", err)
+	}
+	s, _ := c.GetString("merchantdb-postgres", "PASSWORD")
+	if s != "hunter2" || !c.HaveValue("taler", "CURRENCY") {
+		t.Errorf("Inlined files not read after rewrite")
+	}
+	file, _, _ := c.Provenance("merchantdb-postgres", "PASSWORD")
+	if file != filepath.Join(dir, "secret.conf") {
+		t.Errorf("Secret moved to %s", file)
+	}
+	var buf bytes.Buffer
+	c.WriteDiff(&buf)
+	if buf.String() != expected {
+		t.Errorf("Rewrite not stable:\n%s", buf.String())
+	}
+}
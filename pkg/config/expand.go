@@ -0,0 +1,111 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Maximum nesting depth of variable expansion
+const maxExpansionDepth = 128
+
+func isVarChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// Expands $VAR, ${VAR} and ${VAR:-default} in s.
+// Variables are looked up in the [PATHS] section first and then in
+// the environment. Values from [PATHS] and defaults are expanded
+// recursively.
+func (c *Config) ExpandDollar(s string) (string, error) {
+	return c.expandDollar(s, 0)
+}
+
+func (c *Config) expandDollar(s string, depth int) (string, error) {
+	if depth > maxExpansionDepth {
+		return "", fmt.Errorf("variable expansion of %s nested too deeply", s)
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		var name, def string
+		hasDefault := false
+		if i+1 < len(s) && s[i+1] == '{' {
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return "", fmt.Errorf("missing closing brace in %s", s)
+			}
+			inner := s[i+2 : end]
+			name, def, hasDefault = strings.Cut(inner, ":-")
+			i = end + 1
+		} else {
+			j := i + 1
+			for j < len(s) && isVarChar(s[j]) {
+				j++
+			}
+			name = s[i+1 : j]
+			i = j
+		}
+		if name == "" {
+			return "", fmt.Errorf("empty variable name in %s", s)
+		}
+		value, err := c.lookupVariable(name, def, hasDefault, depth)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+	}
+	return b.String(), nil
+}
+
+// Returns the index of the brace closing the one at s[open]
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func (c *Config) lookupVariable(name string, def string, hasDefault bool, depth int) (string, error) {
+	if e := c.lookup("PATHS", name); e != nil {
+		return c.expandDollar(e.value, depth+1)
+	}
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+	if hasDefault {
+		return c.expandDollar(def, depth+1)
+	}
+	return "", fmt.Errorf("variable %s is neither set in [PATHS] nor in the environment", name)
+}
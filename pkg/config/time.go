@@ -0,0 +1,116 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The relative time "forever"
+const Forever = time.Duration(math.MaxInt64)
+
+var timeUnits = map[string]time.Duration{
+	"us":      time.Microsecond,
+	"ms":      time.Millisecond,
+	"s":       time.Second,
+	"\"":      time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"'":       time.Minute,
+	"h":       time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       24 * time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+	"year":    31536000 * time.Second,
+	"years":   31536000 * time.Second,
+	"a":       31536000 * time.Second,
+}
+
+// Parses a relative time such as "5 s", "1 h 30 min" or "forever".
+// A number without unit is in microseconds.
+func ParseRelativeTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "forever") {
+		return Forever, nil
+	}
+	tokens := strings.Fields(s)
+	if len(tokens) == 0 {
+		return 0, fmt.Errorf("empty relative time")
+	}
+	var total time.Duration
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		j := 0
+		for j < len(tok) && '0' <= tok[j] && tok[j] <= '9' {
+			j++
+		}
+		if j == 0 {
+			return 0, fmt.Errorf("invalid relative time %s", s)
+		}
+		n, err := strconv.ParseUint(tok[:j], 10, 63)
+		if err != nil {
+			return 0, fmt.Errorf("invalid relative time %s: %w", s, err)
+		}
+		unitName := tok[j:]
+		if unitName == "" && i+1 < len(tokens) {
+			i++
+			unitName = tokens[i]
+		}
+		unit := time.Microsecond
+		if unitName != "" {
+			u, ok := timeUnits[unitName]
+			if !ok {
+				return 0, fmt.Errorf("unknown time unit %s in %s", unitName, s)
+			}
+			unit = u
+		}
+		if n > uint64(math.MaxInt64/unit) {
+			return 0, fmt.Errorf("relative time %s too large", s)
+		}
+		term := time.Duration(n) * unit
+		if total > Forever-term {
+			return 0, fmt.Errorf("relative time %s too large", s)
+		}
+		total += term
+	}
+	return total, nil
+}
+
+// Returns the value of an option as relative time, see ParseRelativeTime
+func (c *Config) GetRelativeTime(section string, option string) (time.Duration, error) {
+	s, err := c.GetString(section, option)
+	if err != nil {
+		return 0, err
+	}
+	d, err := ParseRelativeTime(s)
+	if err != nil {
+		return 0, fmt.Errorf("[%s]/%s: %w", section, option, err)
+	}
+	return d, nil
+}
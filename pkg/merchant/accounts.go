@@ -0,0 +1,153 @@
+package merchant
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Types of credit facade credentials
+const (
+	FacadeCredentialsNone   = "none"
+	FacadeCredentialsBasic  = "basic"
+	FacadeCredentialsBearer = "bearer"
+)
+
+type FacadeCredentials struct {
+	// One of FacadeCredentialsNone, FacadeCredentialsBasic or
+	// FacadeCredentialsBearer.
+	Type string `json:"type"`
+
+	// Username for basic authentication.
+	Username string `json:"username,omitempty"`
+
+	// Password for basic authentication.
+	Password string `json:"password,omitempty"`
+
+	// Token for bearer authentication.
+	Token string `json:"token,omitempty"`
+}
+
+type AccountAddDetails struct {
+	// payto:// URI of the account.
+	PaytoUri string `json:"payto_uri"`
+
+	// URL from where the merchant can download information
+	// about incoming wire transfers to this account.
+	CreditFacadeUrl string `json:"credit_facade_url,omitempty"`
+
+	// Credentials to use when accessing the credit facade.
+	// Never returned on a GET (as this may be somewhat
+	// sensitive data). Can be set in POST
+	// or PATCH requests to update (or delete) credentials.
+	// To really delete credentials, set them to the type: "none".
+	CreditFacadeCredentials *FacadeCredentials `json:"credit_facade_credentials,omitempty"`
+}
+
+type AccountAddResponse struct {
+	// Hash over the wire details (including over the salt).
+	HWire string `json:"h_wire"`
+
+	// Salt used to compute h_wire.
+	Salt string `json:"salt"`
+}
+
+type AccountPatchDetails struct {
+	// URL from where the merchant can download information
+	// about incoming wire transfers to this account.
+	CreditFacadeUrl string `json:"credit_facade_url,omitempty"`
+
+	// Credentials to use when accessing the credit facade.
+	// To really delete credentials, set them to the type: "none".
+	// If the argument is omitted, the old credentials
+	// are simply preserved.
+	CreditFacadeCredentials *FacadeCredentials `json:"credit_facade_credentials,omitempty"`
+}
+
+type BankAccountEntry struct {
+	// payto:// URI of the account.
+	PaytoUri string `json:"payto_uri"`
+
+	// Hash over the wire details (including over the salt).
+	HWire string `json:"h_wire"`
+
+	// true if this account is active,
+	// false if it is historic.
+	Active bool `json:"active"`
+}
+
+type AccountsSummaryResponse struct {
+	// List of accounts that are known for the instance.
+	Accounts []BankAccountEntry `json:"accounts"`
+}
+
+type BankAccountDetail struct {
+	// payto:// URI of the account.
+	PaytoUri string `json:"payto_uri"`
+
+	// Hash over the wire details (including over the salt).
+	HWire string `json:"h_wire"`
+
+	// Salt used to compute h_wire.
+	Salt string `json:"salt"`
+
+	// URL from where the merchant can download information
+	// about incoming wire transfers to this account.
+	CreditFacadeUrl string `json:"credit_facade_url,omitempty"`
+
+	// true if this account is active,
+	// false if it is historic.
+	Active bool `json:"active"`
+}
+
+func accountPath(hWire string) string {
+	return "/private/accounts/" + url.PathEscape(hWire)
+}
+
+// Add a bank account (POST /private/accounts).
+// The payto URI is validated before it is submitted.
+func (m *Merchant) AddAccount(account AccountAddDetails) (*AccountAddResponse, error) {
+	_, err := util.ParsePaytoUri(account.PaytoUri)
+	if err != nil {
+		return nil, err
+	}
+	var accountResponse AccountAddResponse
+	_, err = m.doRequest(http.MethodPost, "/private/accounts", nil, account, &accountResponse, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &accountResponse, nil
+}
+
+// List the bank accounts (GET /private/accounts)
+func (m *Merchant) GetAccounts() (*AccountsSummaryResponse, error) {
+	var accounts AccountsSummaryResponse
+	_, err := m.doRequest(http.MethodGet, "/private/accounts", nil, nil, &accounts)
+	if err != nil {
+		return nil, err
+	}
+	return &accounts, nil
+}
+
+// Get the details of a bank account (GET /private/accounts/$H_WIRE)
+func (m *Merchant) GetAccount(hWire string) (*BankAccountDetail, error) {
+	var account BankAccountDetail
+	_, err := m.doRequest(http.MethodGet, accountPath(hWire), nil, nil, &account)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Update the credit facade of a bank account (PATCH /private/accounts/$H_WIRE)
+func (m *Merchant) UpdateAccount(hWire string, account AccountPatchDetails) error {
+	_, err := m.doRequest(http.MethodPatch, accountPath(hWire), nil, account, nil)
+	return err
+}
+
+// Deactivate a bank account (DELETE /private/accounts/$H_WIRE)
+func (m *Merchant) DeleteAccount(hWire string) error {
+	_, err := m.doRequest(http.MethodDelete, accountPath(hWire), nil, nil, nil)
+	return err
+}
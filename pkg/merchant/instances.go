@@ -0,0 +1,302 @@
+package merchant
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Authentication methods of an instance
+const (
+	AuthMethodExternal = "external"
+	AuthMethodToken    = "token"
+)
+
+type InstanceAuthConfigurationMessage struct {
+	// Type of authentication.
+	// "external":  The mechanism is implemented by the reverse proxy.
+	// "token":  The merchant checks an auth token.
+	Method string `json:"method"`
+
+	// For method "token", the password or access token
+	// (older backends expect "secret-token:" + token here).
+	Token string `json:"token,omitempty"`
+
+	// For method "token", the password (newer backends).
+	Password string `json:"password,omitempty"`
+}
+
+type Location struct {
+	// Nation with its own government.
+	Country string `json:"country,omitempty"`
+
+	// Identifies a country subdivision.
+	CountrySubdivision string `json:"country_subdivision,omitempty"`
+
+	// Identifies a subdivision of a country subdivision.
+	District string `json:"district,omitempty"`
+
+	// Name of a town.
+	Town string `json:"town,omitempty"`
+
+	// Name of a district of the town.
+	TownLocation string `json:"town_location,omitempty"`
+
+	// Zip code.
+	PostCode string `json:"post_code,omitempty"`
+
+	// Street name.
+	Street string `json:"street,omitempty"`
+
+	// Name of the building.
+	BuildingName string `json:"building_name,omitempty"`
+
+	// Number of the building.
+	BuildingNumber string `json:"building_number,omitempty"`
+
+	// Free-form address lines, should not exceed 7 elements.
+	AddressLines []string `json:"address_lines,omitempty"`
+}
+
+type InstanceConfigurationMessage struct {
+	// Name of the merchant instance to create (will become $INSTANCE).
+	// Must match the regex ^[A-Za-z0-9][A-Za-z0-9_.@-]+$.
+	Id string `json:"id"`
+
+	// Merchant name corresponding to this instance.
+	Name string `json:"name"`
+
+	// Type of the user (business or individual).
+	UserType string `json:"user_type,omitempty"`
+
+	// Merchant email for customer contact.
+	Email string `json:"email,omitempty"`
+
+	// Merchant phone number for customer contact.
+	PhoneNumber string `json:"phone_number,omitempty"`
+
+	// Merchant public website.
+	Website string `json:"website,omitempty"`
+
+	// Merchant logo (image data URL).
+	Logo string `json:"logo,omitempty"`
+
+	// Authentication settings for this instance.
+	Auth InstanceAuthConfigurationMessage `json:"auth"`
+
+	// The merchant's physical address (to be put into contracts).
+	Address Location `json:"address"`
+
+	// The jurisdiction under which the merchant conducts its business
+	// (to be put into contracts).
+	Jurisdiction Location `json:"jurisdiction"`
+
+	// Use STEFAN curves to determine default fees?
+	UseStefan bool `json:"use_stefan"`
+
+	// If the frontend does NOT specify an execution date, how long should
+	// the exchange wait after the deposit before making the wire transfer?
+	DefaultWireTransferDelay util.RelativeTime `json:"default_wire_transfer_delay"`
+
+	// If the frontend does NOT specify a payment deadline, how long should
+	// offers we make be valid by default?
+	DefaultPayDelay util.RelativeTime `json:"default_pay_delay"`
+}
+
+type InstanceReconfigurationMessage struct {
+	// Merchant name corresponding to this instance.
+	Name string `json:"name"`
+
+	// Type of the user (business or individual).
+	UserType string `json:"user_type,omitempty"`
+
+	// Merchant email for customer contact.
+	Email string `json:"email,omitempty"`
+
+	// Merchant phone number for customer contact.
+	PhoneNumber string `json:"phone_number,omitempty"`
+
+	// Merchant public website.
+	Website string `json:"website,omitempty"`
+
+	// Merchant logo (image data URL).
+	Logo string `json:"logo,omitempty"`
+
+	// The merchant's physical address (to be put into contracts).
+	Address Location `json:"address"`
+
+	// The jurisdiction under which the merchant conducts its business
+	// (to be put into contracts).
+	Jurisdiction Location `json:"jurisdiction"`
+
+	// Use STEFAN curves to determine default fees?
+	UseStefan bool `json:"use_stefan"`
+
+	// If the frontend does NOT specify an execution date, how long should
+	// the exchange wait after the deposit before making the wire transfer?
+	DefaultWireTransferDelay util.RelativeTime `json:"default_wire_transfer_delay"`
+
+	// If the frontend does NOT specify a payment deadline, how long should
+	// offers we make be valid by default?
+	DefaultPayDelay util.RelativeTime `json:"default_pay_delay"`
+}
+
+type Instance struct {
+	// Merchant name corresponding to this instance.
+	Name string `json:"name"`
+
+	// Type of the user (business or individual).
+	UserType string `json:"user_type,omitempty"`
+
+	// Merchant public website.
+	Website string `json:"website,omitempty"`
+
+	// Merchant logo.
+	Logo string `json:"logo,omitempty"`
+
+	// Merchant instance this response is about ($INSTANCE).
+	Id string `json:"id"`
+
+	// Public key of the merchant/instance, in Crockford Base32 encoding.
+	MerchantPub string `json:"merchant_pub"`
+
+	// List of the payment targets supported by this instance.
+	PaymentTargets []string `json:"payment_targets"`
+
+	// Has this instance been deleted (but not purged)?
+	Deleted bool `json:"deleted"`
+}
+
+type InstancesResponse struct {
+	// List of instances that are present in the backend.
+	Instances []Instance `json:"instances"`
+}
+
+type QueryInstancesResponse struct {
+	// Merchant name corresponding to this instance.
+	Name string `json:"name"`
+
+	// Type of the user (business or individual).
+	UserType string `json:"user_type,omitempty"`
+
+	// Merchant email for customer contact.
+	Email string `json:"email,omitempty"`
+
+	// Merchant phone number for customer contact.
+	PhoneNumber string `json:"phone_number,omitempty"`
+
+	// Merchant public website.
+	Website string `json:"website,omitempty"`
+
+	// Merchant logo.
+	Logo string `json:"logo,omitempty"`
+
+	// Public key of the merchant/instance, in Crockford Base32 encoding.
+	MerchantPub string `json:"merchant_pub"`
+
+	// The merchant's physical address (to be put into contracts).
+	Address Location `json:"address"`
+
+	// The jurisdiction under which the merchant conducts its business
+	// (to be put into contracts).
+	Jurisdiction Location `json:"jurisdiction"`
+
+	// Use STEFAN curves to determine default fees?
+	UseStefan bool `json:"use_stefan"`
+
+	// If the frontend does NOT specify an execution date, how long should
+	// the exchange wait after the deposit before making the wire transfer?
+	DefaultWireTransferDelay util.RelativeTime `json:"default_wire_transfer_delay"`
+
+	// If the frontend does NOT specify a payment deadline, how long should
+	// offers we make be valid by default?
+	DefaultPayDelay util.RelativeTime `json:"default_pay_delay"`
+
+	// Authentication configuration.
+	// Does not contain the token when token auth is configured.
+	Auth InstanceAuthConfigurationMessage `json:"auth"`
+}
+
+// Returns the query for DELETE requests, optionally purging the data
+func purgeQuery(purge bool) url.Values {
+	if !purge {
+		return nil
+	}
+	return url.Values{"purge": {"YES"}}
+}
+
+// Create a new instance (POST /management/instances).
+// Must be called on the client of the default instance.
+func (m *Merchant) CreateInstance(instance InstanceConfigurationMessage) error {
+	_, err := m.doRequest(http.MethodPost, "/management/instances", nil, instance, nil)
+	return err
+}
+
+// List all instances (GET /management/instances)
+func (m *Merchant) GetInstances() (*InstancesResponse, error) {
+	var instances InstancesResponse
+	_, err := m.doRequest(http.MethodGet, "/management/instances", nil, nil, &instances)
+	if err != nil {
+		return nil, err
+	}
+	return &instances, nil
+}
+
+// Get the configuration of an instance (GET /management/instances/$ID)
+func (m *Merchant) GetInstance(instanceId string) (*QueryInstancesResponse, error) {
+	var instance QueryInstancesResponse
+	_, err := m.doRequest(http.MethodGet, "/management/instances/"+url.PathEscape(instanceId), nil, nil, &instance)
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// Update the configuration of an instance (PATCH /management/instances/$ID)
+func (m *Merchant) UpdateInstance(instanceId string, instance InstanceReconfigurationMessage) error {
+	_, err := m.doRequest(http.MethodPatch, "/management/instances/"+url.PathEscape(instanceId), nil, instance, nil)
+	return err
+}
+
+// Delete an instance (DELETE /management/instances/$ID).
+// If purge is set, all data of the instance is removed.
+func (m *Merchant) DeleteInstance(instanceId string, purge bool) error {
+	_, err := m.doRequest(http.MethodDelete, "/management/instances/"+url.PathEscape(instanceId), purgeQuery(purge), nil, nil)
+	return err
+}
+
+// Update the authentication of an instance
+// (POST /management/instances/$ID/auth)
+func (m *Merchant) UpdateInstanceAuth(instanceId string, auth InstanceAuthConfigurationMessage) error {
+	_, err := m.doRequest(http.MethodPost, "/management/instances/"+url.PathEscape(instanceId)+"/auth", nil, auth, nil)
+	return err
+}
+
+// Get the configuration of this instance (GET /private)
+func (m *Merchant) GetOwnInstance() (*QueryInstancesResponse, error) {
+	var instance QueryInstancesResponse
+	_, err := m.doRequest(http.MethodGet, "/private", nil, nil, &instance)
+	if err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// Update the configuration of this instance (PATCH /private)
+func (m *Merchant) UpdateOwnInstance(instance InstanceReconfigurationMessage) error {
+	_, err := m.doRequest(http.MethodPatch, "/private", nil, instance, nil)
+	return err
+}
+
+// Delete this instance (DELETE /private)
+func (m *Merchant) DeleteOwnInstance(purge bool) error {
+	_, err := m.doRequest(http.MethodDelete, "/private", purgeQuery(purge), nil, nil)
+	return err
+}
+
+// Update the authentication of this instance (POST /private/auth)
+func (m *Merchant) UpdateOwnInstanceAuth(auth InstanceAuthConfigurationMessage) error {
+	_, err := m.doRequest(http.MethodPost, "/private/auth", nil, auth, nil)
+	return err
+}
@@ -0,0 +1,163 @@
+package merchant
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// KYC status of an account at an exchange
+const (
+	KycStatusNoExchangeKeys        = "no-exchange-keys"
+	KycStatusWireImpossible        = "kyc-wire-impossible"
+	KycStatusWireRequired          = "kyc-wire-required"
+	KycStatusRequired              = "kyc-required"
+	KycStatusAwaitingAmlReview     = "awaiting-aml-review"
+	KycStatusReady                 = "ready"
+	KycStatusLogicBug              = "logic-bug"
+	KycStatusExchangeInternalError = "exchange-internal-error"
+	KycStatusExchangeTimeout       = "exchange-gateway-timeout"
+	KycStatusExchangeUnreachable   = "exchange-unreachable"
+	KycStatusExchangeStatusInvalid = "exchange-status-invalid"
+)
+
+type AccountLimit struct {
+	// Operation that is limited.
+	OperationType string `json:"operation_type"`
+
+	// Timeframe during which the limit applies.
+	Timeframe util.RelativeTime `json:"timeframe"`
+
+	// Maximum amount allowed during the given timeframe.
+	Threshold util.Amount `json:"threshold"`
+
+	// True if this is a soft limit that could be raised
+	// by passing KYC checks.
+	SoftLimit bool `json:"soft_limit,omitempty"`
+}
+
+type MerchantAccountKycRedirect struct {
+	// Summary of the status of the KYC process, one of the KycStatus
+	// constants.
+	Status string `json:"status"`
+
+	// Full payto URI of the bank wire account this is about.
+	PaytoUri string `json:"payto_uri"`
+
+	// Hash of the salted payto://-URI of the account.
+	HWire string `json:"h_wire"`
+
+	// Base URL of the exchange this is about.
+	ExchangeUrl string `json:"exchange_url"`
+
+	// HTTP status code returned by the exchange when we asked for
+	// information about the KYC status.
+	ExchangeHttpStatus int `json:"exchange_http_status"`
+
+	// True if we did not get a /keys response from
+	// the exchange and thus cannot do certain checks.
+	NoKeys bool `json:"no_keys"`
+
+	// True if the given account cannot do KYC at the
+	// given exchange because it is used by another merchant.
+	AuthConflict bool `json:"auth_conflict"`
+
+	// Numeric error code indicating errors the exchange
+	// returned, or TALER_EC_INVALID for none.
+	ExchangeCode int `json:"exchange_code,omitempty"`
+
+	// Access token needed to open the KYC SPA and/or
+	// access the /kyc-info/ endpoint.
+	AccessToken string `json:"access_token,omitempty"`
+
+	// Array with limitations that currently apply to this
+	// account and that may be increased or lifted if the
+	// KYC check is passed.
+	Limits []AccountLimit `json:"limits,omitempty"`
+
+	// Array of wire transfer instructions (including
+	// optional amount and subject) for a KYC auth wire
+	// transfer. Set only if this is required
+	// to get the given exchange working.
+	PaytoKycauths []string `json:"payto_kycauths,omitempty"`
+
+	// URL of the KYC process, only returned by older backends.
+	KycUrl string `json:"kyc_url,omitempty"`
+}
+
+type MerchantAccountKycRedirectsResponse struct {
+	// Array of KYC status information for
+	// the exchanges and bank accounts selected
+	// by the query.
+	KycData []MerchantAccountKycRedirect `json:"kyc_data"`
+}
+
+// Filters for GetKycStatus. Zero values are not used for filtering.
+type KycFilter struct {
+	// Only the account with this wire hash.
+	HWire string
+
+	// Only this exchange.
+	ExchangeUrl string
+
+	// How long the backend should wait for the KYC status
+	// to change before answering.
+	Timeout time.Duration
+}
+
+func (f *KycFilter) query() url.Values {
+	q := url.Values{}
+	if f.HWire != "" {
+		q.Set("h_wire", f.HWire)
+	}
+	if f.ExchangeUrl != "" {
+		q.Set("exchange_url", f.ExchangeUrl)
+	}
+	if f.Timeout > 0 {
+		q.Set("timeout_ms", strconv.FormatInt(f.Timeout.Milliseconds(), 10))
+	}
+	return q
+}
+
+// Returns the URL where the seller completes the KYC process,
+// or an empty string if there is none
+func (k *MerchantAccountKycRedirect) KycSpaUrl() string {
+	if k.KycUrl != "" {
+		return k.KycUrl
+	}
+	if k.AccessToken == "" || k.ExchangeUrl == "" {
+		return ""
+	}
+	base := k.ExchangeUrl
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	return base + "kyc-spa/" + k.AccessToken
+}
+
+// Returns true if the seller must act for payments to continue
+func (k *MerchantAccountKycRedirect) ActionRequired() bool {
+	switch k.Status {
+	case KycStatusRequired, KycStatusWireRequired:
+		return true
+	}
+	return false
+}
+
+// Get the KYC status of the accounts of the instance (GET /private/kyc).
+// If filter.Timeout is set, the request is long polling and can be
+// cancelled with ctx.
+// Returns an empty response if no KYC is required.
+func (m *Merchant) GetKycStatus(ctx context.Context, filter KycFilter) (*MerchantAccountKycRedirectsResponse, error) {
+	var kyc MerchantAccountKycRedirectsResponse
+	_, err := m.doRequestContext(ctx, http.MethodGet, "/private/kyc", filter.query(), nil, &kyc)
+	if err != nil {
+		return nil, err
+	}
+	return &kyc, nil
+}
@@ -2,11 +2,16 @@ package merchant
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/schanzen/taler-go/pkg/util"
 )
@@ -43,8 +48,7 @@ type PostOrderRequest struct {
 	// order from the inventory.  For these inventory management
 	// is performed (so the products must be in stock) and
 	// details are completed from the product data of the backend.
-	// FIXME: Not sure we actually need this for now
-	//InventoryProducts []MinimalInventoryProduct `json:"inventory_products,omitempty"`
+	InventoryProducts []MinimalInventoryProduct `json:"inventory_products,omitempty"`
 
 	// Specifies a lock identifier that was used to
 	// lock a product in the inventory.  Only useful if
@@ -64,9 +68,17 @@ type PostOrderRequest struct {
 }
 
 type CommonOrder struct {
+	// Version of the order, OrderVersion0 if omitted.
+	// Orders with OrderVersion1 use Choices instead of Amount.
+	Version int `json:"version,omitempty"`
+
 	// Total price for the transaction. The exchange will subtract deposit
 	// fees from that amount before transferring it to the merchant.
-	Amount string `json:"amount"`
+	// Required for OrderVersion0.
+	Amount string `json:"amount,omitempty"`
+
+	// Possible ways to pay for the order, only for OrderVersion1.
+	Choices []OrderChoice `json:"choices,omitempty"`
 
 	// Maximum total deposit fee accepted by the merchant for this contract.
 	// Overrides defaults of the merchant instance.
@@ -173,6 +185,11 @@ type FulfillmentMetadata struct {
 type PostOrderResponse struct {
 	// Order ID of the response that was just created.
 	OrderId string `json:"order_id"`
+
+	// Token that authorizes the wallet to claim the order.
+	// Provided only if "create_token" was set to 'true'
+	// in the request.
+	Token string `json:"token,omitempty"`
 }
 
 type PostOrderResponseToken struct {
@@ -208,6 +225,117 @@ func NewMerchant(merchBaseUrlPrivate string, merchAccessToken string) Merchant {
 	}
 }
 
+// Returns a client for the instance with the given ID, using the
+// same access token. Set AccessToken on the result if the instance
+// uses its own token.
+func (m *Merchant) Instance(instanceId string) Merchant {
+	return Merchant{
+		BaseUrlPrivate: m.BaseUrlPrivate + "/instances/" + url.PathEscape(instanceId),
+		AccessToken:    m.AccessToken,
+	}
+}
+
+// An error response of the merchant backend
+type MerchantError struct {
+	// The HTTP status code of the response
+	StatusCode int `json:"-"`
+
+	// The Taler error code
+	Code int `json:"code"`
+
+	// Human-readable description of the error
+	Hint string `json:"hint,omitempty"`
+
+	// Optional details about the error
+	Detail string `json:"detail,omitempty"`
+}
+
+func (e *MerchantError) Error() string {
+	if e.Hint == "" {
+		return fmt.Sprintf("Merchant backend returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Merchant backend returned %d: %s (%d)", e.StatusCode, e.Hint, e.Code)
+}
+
+// Performs a request against the merchant backend.
+// body is sent as JSON if not nil, the response is decoded into out
+// if not nil and the response has a body. Responses with a status other
+// than the expected ones (http.StatusOK and http.StatusNoContent if
+// none are given) are returned as *MerchantError.
+// Returns the HTTP status code.
+func (m *Merchant) doRequest(method string, path string, query url.Values, body any, out any, expected ...int) (int, error) {
+	return doRequestContext(context.Background(), m.BaseUrlPrivate, m.AccessToken, method, path, query, body, out, expected...)
+}
+
+// Like doRequest, but the request is cancelled with ctx
+func (m *Merchant) doRequestContext(ctx context.Context, method string, path string, query url.Values, body any, out any, expected ...int) (int, error) {
+	return doRequestContext(ctx, m.BaseUrlPrivate, m.AccessToken, method, path, query, body, out, expected...)
+}
+
+// Time granted to long polling requests beyond their timeout_ms
+const longPollSlack = 5 * time.Second
+
+// Performs a request against the backend at baseUrl, see Merchant.doRequest.
+// The Authorization header is only set if accessToken is not empty.
+func doRequest(baseUrl string, accessToken string, method string, path string, query url.Values, body any, out any, expected ...int) (int, error) {
+	return doRequestContext(context.Background(), baseUrl, accessToken, method, path, query, body, out, expected...)
+}
+
+// Like doRequest, but the request is cancelled with ctx. Long polling
+// requests (with timeout_ms in query) are also cancelled if the backend
+// does not answer within longPollSlack after the timeout.
+func doRequestContext(ctx context.Context, baseUrl string, accessToken string, method string, path string, query url.Values, body any, out any, expected ...int) (int, error) {
+	if timeoutMs, err := strconv.ParseInt(query.Get("timeout_ms"), 10, 64); err == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeoutMs)*time.Millisecond+longPollSlack)
+		defer cancel()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		reqString, err := json.Marshal(body)
+		if nil != err {
+			return 0, err
+		}
+		reqBody = bytes.NewReader(reqString)
+	}
+	reqUrl := baseUrl + path
+	if len(query) != 0 {
+		reqUrl += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, reqBody)
+	if nil != err {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer secret-token:"+accessToken)
+	}
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if nil != err {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if len(expected) == 0 {
+		expected = []int{http.StatusOK, http.StatusNoContent}
+	}
+	if !slices.Contains(expected, resp.StatusCode) {
+		merchErr := MerchantError{StatusCode: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(&merchErr)
+		return resp.StatusCode, &merchErr
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return resp.StatusCode, nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err == io.EOF {
+		err = nil
+	}
+	return resp.StatusCode, err
+}
+
 type PaymentStatus string
 
 const (
@@ -296,6 +424,17 @@ func (m *Merchant) CreateOrder(order CommonOrder) (string, error) {
 	return orderResponse.OrderId, err
 }
 
+// Create an order from a full order request, e.g. with inventory
+// products and lock UUIDs (POST /private/orders)
+func (m *Merchant) PostOrder(order PostOrderRequest) (*PostOrderResponse, error) {
+	var orderResponse PostOrderResponse
+	_, err := m.doRequest(http.MethodPost, "/private/orders", nil, order, &orderResponse, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &orderResponse, nil
+}
+
 func (m *Merchant) AddNewOrder(cost util.Amount, summary string, fulfillment_url string) (string, error) {
 	var newOrder PostOrderRequest
 	var orderDetail CommonOrder
@@ -0,0 +1,477 @@
+package merchant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Starts a test backend answering every request with handler
+func testMerchant(t *testing.T, handler http.HandlerFunc) Merchant {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewMerchant(srv.URL, "sandbox")
+}
+
+func TestInstances(t *testing.T) {
+	var lastPath, lastAuth, lastQuery string
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.Method + " " + r.URL.Path
+		lastAuth = r.Header.Get("Authorization")
+		lastQuery = r.URL.RawQuery
+		switch r.URL.Path {
+		case "/management/instances":
+			if r.Method == http.MethodGet {
+				json.NewEncoder(w).Encode(InstancesResponse{Instances: []Instance{{Id: "shop"}}})
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case "/instances/shop/private":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code": 2000, "hint": "instance unknown"}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	err := m.CreateInstance(InstanceConfigurationMessage{Id: "shop", Auth: InstanceAuthConfigurationMessage{Method: AuthMethodExternal}})
+	if err != nil || lastPath != "POST /management/instances" || lastAuth != "Bearer secret-token:sandbox" {
+		t.Errorf("Unexpected request %s (%s): %v", lastPath, lastAuth, err)
+	}
+	instances, err := m.GetInstances()
+	if err != nil || len(instances.Instances) != 1 || instances.Instances[0].Id != "shop" {
+		t.Errorf("Unexpected instances %v: %v", instances, err)
+	}
+	err = m.DeleteInstance("shop", true)
+	if err != nil || lastPath != "DELETE /management/instances/shop" || lastQuery != "purge=YES" {
+		t.Errorf("Unexpected request %s?%s: %v", lastPath, lastQuery, err)
+	}
+	shop := m.Instance("shop")
+	_, err = shop.GetOwnInstance()
+	var merchErr *MerchantError
+	if !errors.As(err, &merchErr) || merchErr.StatusCode != http.StatusNotFound || merchErr.Code != 2000 {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestInventoryOrder(t *testing.T) {
+	var order map[string]any
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/private/products/coffee":
+			json.NewEncoder(w).Encode(map[string]any{
+				"description": "Coffee",
+				"unit":        "cup",
+				"price":       "EUR:2.5",
+				"total_stock": -1,
+				"total_sold":  3,
+				"total_lost":  0,
+			})
+		case "/private/orders":
+			json.NewDecoder(r.Body).Decode(&order)
+			json.NewEncoder(w).Encode(PostOrderResponse{OrderId: "2026.1", Token: "tok"})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	product, err := m.GetProduct("coffee")
+	if err != nil || product.Price.String() != "EUR:2.5" || product.TotalStock != InfiniteStock {
+		t.Fatalf("Unexpected product %v: %v", product, err)
+	}
+	lockUuid, _ := NewLockUuid()
+	err = m.LockProduct("coffee", LockRequest{LockUuid: lockUuid, Quantity: 2})
+	if err != nil {
+		t.Errorf("Failed locking product: %v", err)
+	}
+	resp, err := m.PostOrder(PostOrderRequest{
+		Order:             CommonOrder{Amount: "EUR:5", Summary: "Coffee"},
+		InventoryProducts: []MinimalInventoryProduct{{ProductId: "coffee", Quantity: 2}},
+		LockUuids:         []string{lockUuid},
+	})
+	if err != nil || resp.OrderId != "2026.1" || resp.Token != "tok" {
+		t.Errorf("Unexpected order response %v: %v", resp, err)
+	}
+	if order["lock_uuids"].([]any)[0] != lockUuid || order["inventory_products"] == nil {
+		t.Errorf("Unexpected order request %v", order)
+	}
+}
+
+func TestAddAccount(t *testing.T) {
+	requests := 0
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(AccountAddResponse{HWire: "H", Salt: "S"})
+	})
+	_, err := m.AddAccount(AccountAddDetails{PaytoUri: "payto://iban/DE89370400440532013001"})
+	if nil == err || requests != 0 {
+		t.Errorf("Invalid payto URI submitted")
+	}
+	resp, err := m.AddAccount(AccountAddDetails{
+		PaytoUri:                "payto://iban/DE89370400440532013000?receiver-name=Shop",
+		CreditFacadeCredentials: &FacadeCredentials{Type: FacadeCredentialsNone},
+	})
+	if err != nil || resp.HWire != "H" || resp.Salt != "S" {
+		t.Errorf("Unexpected response %v: %v", resp, err)
+	}
+}
+
+func TestTransfers(t *testing.T) {
+	var query string
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"total": "EUR:9.8", "wire_fee": "EUR:0.1", "execution_time": {"t_s": 1700000000},
+			  "deposits_sums": [
+			    {"order_id": "a", "deposit_value": "EUR:5", "deposit_fee": "EUR:0.05"},
+			    {"order_id": "b", "deposit_value": "EUR:5", "deposit_fee": "EUR:0.05"}]}`))
+			return
+		}
+		w.Write([]byte(`{"transfers": [{"credit_amount": "EUR:9.8", "wtid": "W", "payto_uri": "payto://iban/X",
+		  "exchange_url": "https://exchange.example.com/", "transfer_serial_id": 7, "verified": true}]}`))
+	})
+	track, err := m.AddTransfer(TransferInformation{})
+	if err != nil {
+		t.Fatalf("Failed adding transfer: %v", err)
+	}
+	value, fees, err := track.DepositTotals()
+	if err != nil || value.String() != "EUR:10" || fees.String() != "EUR:0.1" {
+		t.Errorf("Unexpected totals %v %v: %v", value, fees, err)
+	}
+	transfers, err := m.GetTransfers(TransferFilter{Limit: -20, Verified: "YES"})
+	if err != nil || len(transfers.Transfers) != 1 || !*transfers.Transfers[0].Verified {
+		t.Errorf("Unexpected transfers %v: %v", transfers, err)
+	}
+	if query != "limit=-20&verified=YES" {
+		t.Errorf("Unexpected query %s", query)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	var lastPath, lastAuth string
+	var body UsingTemplateDetails
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		lastPath = r.Method + " " + r.URL.Path
+		lastAuth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/templates/vending":
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`{"template_contract": {"summary": "Soda", "minimum_age": 0, "pay_duration": {"d_us": 1}}}`))
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(PostOrderResponse{OrderId: "2026.1", Token: "T"})
+		case "/private/otp-devices/machine":
+			w.Write([]byte(`{"device_description": "Machine", "otp_algorithm": "TOTP_WITHOUT_PRICE", "otp_timestamp": 1}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	err := m.AddTemplate(TemplateAddDetails{TemplateId: "vending", OtpId: "machine"})
+	if err != nil || lastPath != "POST /private/templates" {
+		t.Errorf("Failed adding template: %v", err)
+	}
+	device, err := m.GetOtpDevice("machine", nil)
+	if err != nil || device.OtpAlgorithm != OtpAlgorithmTotpWithoutPrice {
+		t.Errorf("Failed getting OTP device: %v", err)
+	}
+	pub := NewPublicClient(m.BaseUrlPrivate)
+	template, err := pub.GetWalletTemplate("vending")
+	if err != nil || template.TemplateContract.Summary != "Soda" || lastAuth != "" {
+		t.Errorf("Failed getting wallet template %s: %v", lastAuth, err)
+	}
+	amount, _ := util.ParseAmount("EUR:1.5")
+	order, err := pub.UseTemplate("vending", UsingTemplateDetails{Amount: amount})
+	if err != nil || order.OrderId != "2026.1" || order.Token != "T" {
+		t.Errorf("Failed using template: %v", err)
+	}
+	if lastAuth != "" || body.Amount == nil || body.Amount.String() != "EUR:1.5" {
+		t.Errorf("Unexpected template request %s %v", lastAuth, body)
+	}
+}
+
+func TestTotp(t *testing.T) {
+	// RFC 6238 test vectors for SHA1
+	key := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	for ts, expected := range map[int64]string{
+		59:         "94287082",
+		1111111109: "07081804",
+		2000000000: "69279037",
+	} {
+		code, err := PosConfirmation(key, OtpAlgorithmTotpWithoutPrice, nil, time.Unix(ts, 0))
+		if err != nil || code != expected {
+			t.Errorf("Expected %s at %d, got %s: %v", expected, ts, code, err)
+		}
+	}
+	// TOTP_WITH_PRICE codes for the RFC 6238 key, computed independently
+	// following TALER_build_pos_confirmation
+	for _, v := range []struct {
+		price    string
+		ts       int64
+		expected string
+	}{
+		{"EUR:1.5", 59, "83887842"},
+		{"EUR:1.5", 1111111109, "93076942"},
+		{"EUR:1.5", 2000000000, "19894789"},
+		{"KUDOS:10", 59, "83610321"},
+		{"KUDOS:10", 1111111109, "14151087"},
+		{"KUDOS:10", 2000000000, "27688203"},
+	} {
+		price, _ := util.ParseAmount(v.price)
+		code, err := PosConfirmation(key, OtpAlgorithmTotpWithPrice, price, time.Unix(v.ts, 0))
+		if err != nil || code != v.expected {
+			t.Errorf("Expected %s for %s at %d, got %s: %v", v.expected, v.price, v.ts, code, err)
+		}
+	}
+	_, err := PosConfirmation(key, OtpAlgorithmTotpWithPrice, nil, time.Now())
+	if nil == err {
+		t.Errorf("Missing price accepted")
+	}
+	price := util.NewAmount("VERYLONGCURR", 1, 0)
+	_, err = PosConfirmation(key, OtpAlgorithmTotpWithPrice, &price, time.Now())
+	if nil == err {
+		t.Errorf("Overlong currency accepted")
+	}
+	code, err := PosConfirmation(key, OtpAlgorithmNone, nil, time.Now())
+	if err != nil || code != "" {
+		t.Errorf("Unexpected code %s for no algorithm: %v", code, err)
+	}
+	_, err = PosConfirmation(key, OtpAlgorithm(3), nil, time.Now())
+	if !errors.Is(err, ErrOtpAlgorithmUnsupported) {
+		t.Errorf("Unknown algorithm accepted")
+	}
+}
+
+func TestWebhookReceiver(t *testing.T) {
+	var paid, refunded string
+	receiver := &WebhookReceiver{
+		Secret: "s3cret",
+		OnPay: func(ctx context.Context, event PayEvent) error {
+			paid = event.OrderId
+			return nil
+		},
+		OnRefund: func(ctx context.Context, event RefundEvent) error {
+			refunded = event.OrderId + " " + event.RefundAmount.String()
+			return nil
+		},
+	}
+	webhook := receiver.Webhook("pay-hook", WebhookEventPay, "https://shop.example.com/hook")
+	if webhook.BodyTemplate != PayWebhookBodyTemplate || !strings.Contains(webhook.HeaderTemplate, "Taler-Webhook-Secret: s3cret") {
+		t.Errorf("Unexpected webhook %v", webhook)
+	}
+	post := func(secret string, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		req.Header.Set(DefaultWebhookSecretHeader, secret)
+		rec := httptest.NewRecorder()
+		receiver.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := post("wrong", `{"event_type":"pay","order_id":"1"}`); code != http.StatusUnauthorized || paid != "" {
+		t.Errorf("Wrong secret accepted: %d", code)
+	}
+	if code := post("s3cret", `{"event_type":"pay","order_id":"1","contract_terms":{"amount":"EUR:1"}}`); code != http.StatusNoContent || paid != "1" {
+		t.Errorf("Failed receiving pay event: %d", code)
+	}
+	if code := post("s3cret", `{"event_type":"refund","order_id":"2","refund_amount":"EUR:0.5","timestamp":{"t_s":1}}`); code != http.StatusNoContent || refunded != "2 EUR:0.5" {
+		t.Errorf("Failed receiving refund event: %d %s", code, refunded)
+	}
+	if code := post("s3cret", `{"event_type":"unknown"}`); code != http.StatusBadRequest {
+		t.Errorf("Unknown event accepted: %d", code)
+	}
+	if code := post("s3cret", `{"event_type":"pay","order_id":"`+strings.Repeat("x", maxWebhookBodySize)+`"}`); code != http.StatusBadRequest {
+		t.Errorf("Oversized body accepted: %d", code)
+	}
+	var logs bytes.Buffer
+	receiver.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	receiver.OnPay = func(ctx context.Context, event PayEvent) error {
+		return errors.New("open /var/lib/shop/orders.db: permission denied")
+	}
+	req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(`{"event_type":"pay","order_id":"3"}`))
+	req.Header.Set(DefaultWebhookSecretHeader, "s3cret")
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "orders.db") {
+		t.Errorf("Unexpected callback failure response %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(logs.String(), "orders.db") {
+		t.Errorf("Callback error not logged: %s", logs.String())
+	}
+	refundBody := strings.NewReplacer(
+		"{{order_id}}", "2",
+		"{{refund_amount}}", "EUR:0.5",
+		"{{{timestamp}}}", `{"t_s":1}`,
+		"{{{contract_terms}}}", `{"summary":"say \"hi\"\n"}`,
+	).Replace(RefundWebhookBodyTemplate)
+	if strings.Contains(refundBody, "{{") || post("s3cret", refundBody) != http.StatusNoContent {
+		t.Errorf("Failed receiving templated refund event: %s", refundBody)
+	}
+}
+
+func TestSubscriptionOrder(t *testing.T) {
+	var order map[string]any
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&order)
+		json.NewEncoder(w).Encode(PostOrderResponse{OrderId: "sub"})
+	})
+	price, _ := util.ParseAmount("EUR:10")
+	free, _ := util.ParseAmount("EUR:0")
+	_, err := m.PostOrder(PostOrderRequest{Order: CommonOrder{
+		Version: OrderVersion1,
+		Summary: "Monthly magazine",
+		Choices: []OrderChoice{
+			{Amount: *free, Inputs: []OrderInput{TokenInput("monthly", 1)}},
+			{Amount: *price, Outputs: []OrderOutput{TokenOutput("monthly", 1)}},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Failed posting order: %v", err)
+	}
+	o := order["order"].(map[string]any)
+	if _, ok := o["amount"]; ok || o["version"] != float64(1) {
+		t.Errorf("Unexpected v1 order %v", o)
+	}
+	choices := o["choices"].([]any)
+	input := choices[0].(map[string]any)["inputs"].([]any)[0].(map[string]any)
+	if len(choices) != 2 || input["type"] != "token" || input["token_family_slug"] != "monthly" {
+		t.Errorf("Unexpected choices %v", choices)
+	}
+	if choices[1].(map[string]any)["amount"] != "EUR:10" {
+		t.Errorf("Unexpected choice amount %v", choices[1])
+	}
+}
+
+func TestKycStatus(t *testing.T) {
+	var query string
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if r.URL.Query().Get("timeout_ms") == "60000" {
+			// Stalled backend
+			<-r.Context().Done()
+			return
+		}
+		if r.URL.Query().Get("h_wire") == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"kyc_data": [{"status": "kyc-required", "payto_uri": "payto://iban/X", "h_wire": "H",
+		  "exchange_url": "https://exchange.example.com", "exchange_http_status": 200, "no_keys": false,
+		  "auth_conflict": false, "access_token": "ABC",
+		  "limits": [{"operation_type": "DEPOSIT", "timeframe": {"d_us": "forever"}, "threshold": "EUR:1000"}]}]}`))
+	})
+	kyc, err := m.GetKycStatus(context.Background(), KycFilter{})
+	if err != nil || len(kyc.KycData) != 0 {
+		t.Errorf("Failed getting empty KYC status: %v", err)
+	}
+	kyc, err = m.GetKycStatus(context.Background(), KycFilter{HWire: "H", Timeout: 30 * time.Second})
+	if err != nil || len(kyc.KycData) != 1 {
+		t.Fatalf("Failed getting KYC status: %v", err)
+	}
+	if query != "h_wire=H&timeout_ms=30000" {
+		t.Errorf("Unexpected query %s", query)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = m.GetKycStatus(ctx, KycFilter{Timeout: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Long poll not cancelled: %v", err)
+	}
+	entry := kyc.KycData[0]
+	if !entry.ActionRequired() || entry.KycSpaUrl() != "https://exchange.example.com/kyc-spa/ABC" {
+		t.Errorf("Unexpected KYC entry %v", entry)
+	}
+	if !entry.Limits[0].Timeframe.Forever || entry.Limits[0].Threshold.String() != "EUR:1000" {
+		t.Errorf("Unexpected limits %v", entry.Limits)
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	var query string
+	m := testMerchant(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		switch r.URL.Path {
+		case "/private/statistics-amount/deposits":
+			w.Write([]byte(`{"buckets": [
+			  {"start_time": {"t_s": 0}, "end_time": {"t_s": 3600}, "range": "hour", "cumulative_amounts": ["EUR:1.5", "CHF:2"]},
+			  {"start_time": {"t_s": 3600}, "end_time": {"t_s": 7200}, "range": "hour", "cumulative_amounts": ["EUR:0.5"]}],
+			  "intervals": [{"start_time": {"t_s": 0}, "cumulative_amounts": ["EUR:2"]}]}`))
+		case "/private/orders":
+			w.Write([]byte(`{"orders": [
+			  {"order_id": "a", "row_id": 1, "timestamp": {"t_s": 1}, "amount": "EUR:3", "summary": "A", "refundable": false, "paid": true},
+			  {"order_id": "b", "row_id": 2, "timestamp": {"t_s": 2}, "amount": "EUR:4", "summary": "B", "refundable": false, "paid": false},
+			  {"order_id": "c", "row_id": 3, "timestamp": {"t_s": 3}, "amount": "CHF:1.25", "summary": "C", "refundable": true, "paid": true}]}`))
+		}
+	})
+	stats, err := m.GetStatisticsAmount("deposits", StatisticsByBucket)
+	if err != nil || query != "by=BUCKET" {
+		t.Fatalf("Failed getting statistics: %v", err)
+	}
+	totals, err := stats.BucketTotals()
+	if err != nil || totals["EUR"].String() != "EUR:2" || totals["CHF"].String() != "CHF:2" {
+		t.Errorf("Unexpected bucket totals %v: %v", totals, err)
+	}
+	paid := true
+	orders, err := m.GetOrders(OrderFilter{Paid: &paid, Delta: -20})
+	if err != nil || query != "delta=-20&paid=yes" {
+		t.Fatalf("Failed getting orders (%s): %v", query, err)
+	}
+	totals, err = orders.Totals(true)
+	if err != nil || len(totals) != 2 || totals["EUR"].String() != "EUR:3" || totals["CHF"].String() != "CHF:1.25" {
+		t.Errorf("Unexpected order totals %v: %v", totals, err)
+	}
+}
+
+func TestPublicOrder(t *testing.T) {
+	var lastAuth, query string
+	var claim ClaimRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastAuth = r.Header.Get("Authorization")
+		query = r.URL.RawQuery
+		switch r.URL.Path {
+		case "/orders/2026.1":
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"type": "unpaid", "taler_pay_uri": "taler://pay/shop/2026.1/s1", "already_paid_order_id": "2026.0"}`))
+		case "/orders/2026.1/claim":
+			json.NewDecoder(r.Body).Decode(&claim)
+			w.Write([]byte(`{"contract_terms": {"order_id": "2026.1"}, "sig": "SIG"}`))
+		case "/orders/2026.1/refund":
+			w.Write([]byte(`{"refund_amount": "EUR:1", "merchant_pub": "PUB", "refunds": [{"type": "success",
+			  "exchange_status": 200, "rtransaction_id": 1, "coin_pub": "C", "refund_amount": "EUR:1",
+			  "execution_time": {"t_s": 5}, "exchange_sig": "S", "exchange_pub": "P"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	p := NewPublicClient(srv.URL)
+	code, status, err := p.GetOrder("2026.1", PublicOrderQuery{Token: "T", SessionId: "s1"})
+	if err != nil || code != http.StatusPaymentRequired || !status.AlreadyPaid() || status.AlreadyPaidOrderId != "2026.0" {
+		t.Errorf("Unexpected order status %d %v: %v", code, status, err)
+	}
+	if query != "session_id=s1&token=T" || lastAuth != "" {
+		t.Errorf("Unexpected request %s %s", query, lastAuth)
+	}
+	nonce, err := NewClaimNonce()
+	if err != nil || len(nonce) != 52 {
+		t.Errorf("Unexpected nonce %s: %v", nonce, err)
+	}
+	resp, err := p.ClaimOrder("2026.1", ClaimRequest{Nonce: nonce, Token: "T"})
+	if err != nil || resp.Sig != "SIG" || claim.Nonce != nonce {
+		t.Errorf("Failed claiming order: %v", err)
+	}
+	refund, err := p.ObtainRefund("2026.1", WalletRefundRequest{HContract: "H"})
+	if err != nil || len(refund.Refunds) != 1 || refund.Refunds[0].Type != RefundStatusSuccess {
+		t.Errorf("Failed obtaining refund: %v", err)
+	}
+	_, _, err = p.GetOrder("unknown", PublicOrderQuery{})
+	var merchErr *MerchantError
+	if !errors.As(err, &merchErr) || merchErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
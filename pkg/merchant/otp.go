@@ -0,0 +1,268 @@
+package merchant
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Algorithm used by an OTP device to compute payment confirmations
+type OtpAlgorithm int
+
+// OTP algorithms supported by the merchant backend
+const (
+	// No confirmation codes
+	OtpAlgorithmNone OtpAlgorithm = 0
+
+	// TOTP code without the price
+	OtpAlgorithmTotpWithoutPrice OtpAlgorithm = 1
+
+	// TOTP code including the price
+	OtpAlgorithmTotpWithPrice OtpAlgorithm = 2
+)
+
+var otpAlgorithmNames = map[string]OtpAlgorithm{
+	"NONE":               OtpAlgorithmNone,
+	"TOTP_WITHOUT_PRICE": OtpAlgorithmTotpWithoutPrice,
+	"TOTP_WITH_PRICE":    OtpAlgorithmTotpWithPrice,
+}
+
+// The backend accepts and returns the algorithm either as number
+// or by name
+func (a *OtpAlgorithm) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		alg, ok := otpAlgorithmNames[name]
+		if !ok {
+			return fmt.Errorf("unknown OTP algorithm %s", name)
+		}
+		*a = alg
+		return nil
+	}
+	var num int
+	if err := json.Unmarshal(data, &num); err != nil {
+		return err
+	}
+	*a = OtpAlgorithm(num)
+	return nil
+}
+
+// Time step of TOTP codes
+const TotpStep = 30 * time.Second
+
+// Number of digits of TOTP codes
+const TotpDigits = 8
+
+// Returned for unknown OTP algorithms
+var ErrOtpAlgorithmUnsupported = errors.New("OTP algorithm not supported")
+
+// Maximum length of a currency in the binary amount encoding,
+// including the terminating zero
+const currencyLen = 12
+
+type OtpDeviceAddDetails struct {
+	// Device ID to use.
+	OtpDeviceId string `json:"otp_device_id"`
+
+	// Human-readable description for the device.
+	OtpDeviceDescription string `json:"otp_device_description"`
+
+	// A key encoded with RFC 3548 Base32.
+	// IMPORTANT: This is not using the typical
+	// Taler base32-crockford encoding.
+	// Instead it uses the RFC 3548 encoding to
+	// be compatible with the TOTP standard.
+	OtpKey string `json:"otp_key"`
+
+	// Algorithm for computing the POS confirmation.
+	OtpAlgorithm OtpAlgorithm `json:"otp_algorithm"`
+
+	// Counter for counter-based OTP devices.
+	OtpCtr uint64 `json:"otp_ctr,omitempty"`
+}
+
+type OtpDevicePatchDetails struct {
+	// Human-readable description for the device.
+	OtpDeviceDescription string `json:"otp_device_description"`
+
+	// A key encoded with RFC 3548 Base32.
+	// If not given, the key is not changed.
+	OtpKey string `json:"otp_key,omitempty"`
+
+	// Algorithm for computing the POS confirmation.
+	OtpAlgorithm OtpAlgorithm `json:"otp_algorithm"`
+
+	// Counter for counter-based OTP devices.
+	OtpCtr uint64 `json:"otp_ctr,omitempty"`
+}
+
+type OtpDeviceEntry struct {
+	// Device identifier.
+	OtpDeviceId string `json:"otp_device_id"`
+
+	// Human-readable description for the device.
+	DeviceDescription string `json:"device_description"`
+}
+
+type OtpDeviceSummaryResponse struct {
+	// Array of devices that are present in our backend.
+	OtpDevices []OtpDeviceEntry `json:"otp_devices"`
+}
+
+type OtpDeviceDetails struct {
+	// Human-readable description for the device.
+	DeviceDescription string `json:"device_description"`
+
+	// Algorithm for computing the POS confirmation.
+	OtpAlgorithm OtpAlgorithm `json:"otp_algorithm"`
+
+	// Counter for counter-based OTP devices.
+	OtpCtr uint64 `json:"otp_ctr,omitempty"`
+
+	// The current time as seen by the backend,
+	// used to compute otp_code.
+	OtpTimestamp uint64 `json:"otp_timestamp"`
+
+	// Current confirmation code of the device, only
+	// returned if the backend can compute it.
+	OtpCode string `json:"otp_code,omitempty"`
+}
+
+func otpDevicePath(deviceId string) string {
+	return "/private/otp-devices/" + url.PathEscape(deviceId)
+}
+
+// Add an OTP device (POST /private/otp-devices)
+func (m *Merchant) AddOtpDevice(device OtpDeviceAddDetails) error {
+	_, err := m.doRequest(http.MethodPost, "/private/otp-devices", nil, device, nil)
+	return err
+}
+
+// List the OTP devices (GET /private/otp-devices)
+func (m *Merchant) GetOtpDevices() (*OtpDeviceSummaryResponse, error) {
+	var devices OtpDeviceSummaryResponse
+	_, err := m.doRequest(http.MethodGet, "/private/otp-devices", nil, nil, &devices)
+	if err != nil {
+		return nil, err
+	}
+	return &devices, nil
+}
+
+// Get the details of an OTP device (GET /private/otp-devices/$ID).
+// If price is not nil, the backend computes the confirmation code
+// for a payment of this amount.
+func (m *Merchant) GetOtpDevice(deviceId string, price *util.Amount) (*OtpDeviceDetails, error) {
+	var device OtpDeviceDetails
+	var query url.Values
+	if price != nil {
+		query = url.Values{"price": {price.String()}}
+	}
+	_, err := m.doRequest(http.MethodGet, otpDevicePath(deviceId), query, nil, &device)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// Update an OTP device (PATCH /private/otp-devices/$ID)
+func (m *Merchant) UpdateOtpDevice(deviceId string, device OtpDevicePatchDetails) error {
+	_, err := m.doRequest(http.MethodPatch, otpDevicePath(deviceId), nil, device, nil)
+	return err
+}
+
+// Delete an OTP device (DELETE /private/otp-devices/$ID)
+func (m *Merchant) DeleteOtpDevice(deviceId string) error {
+	_, err := m.doRequest(http.MethodDelete, otpDevicePath(deviceId), nil, nil, nil)
+	return err
+}
+
+// Decodes an RFC 3548 base32 OTP key (case-insensitive, padding optional)
+func DecodeOtpKey(key string) ([]byte, error) {
+	key = strings.TrimRight(strings.ToUpper(strings.ReplaceAll(key, " ", "")), "=")
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(key)
+}
+
+// Computes the RFC 6238 TOTP code (HMAC-SHA1, TotpStep, TotpDigits)
+// for the given raw key at time t
+func Totp(key []byte, t time.Time) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(t.Unix()/int64(TotpStep/time.Second)))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TotpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TotpDigits, code%mod)
+}
+
+// Derives the key of OtpAlgorithmTotpWithPrice codes from the device
+// key and the price like the merchant backend: HKDF with HMAC-SHA512
+// for extraction, HMAC-SHA256 for expansion, the price in network byte
+// order (value, fraction, zero-padded currency) as salt and no context.
+func priceKey(rawKey []byte, price util.Amount) ([]byte, error) {
+	if len(price.Currency) >= currencyLen {
+		return nil, fmt.Errorf("currency %s too long", price.Currency)
+	}
+	if price.Value >= util.MaxAmountValue || price.Fraction >= util.FractionalBase {
+		return nil, fmt.Errorf("invalid amount %s", price.String())
+	}
+	var salt [8 + 4 + currencyLen]byte
+	binary.BigEndian.PutUint64(salt[0:8], price.Value)
+	binary.BigEndian.PutUint32(salt[8:12], uint32(price.Fraction))
+	copy(salt[12:], price.Currency)
+	prk, err := hkdf.Extract(sha512.New, rawKey, salt[:])
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Expand(sha256.New, prk, "", sha512.Size)
+}
+
+// Computes the payment confirmation code an OTP device with the
+// given base32 key shows at time t for a payment of price.
+// price is only used by OtpAlgorithmTotpWithPrice, which requires it.
+// Returns an empty code for OtpAlgorithmNone.
+func PosConfirmation(key string, algorithm OtpAlgorithm, price *util.Amount, t time.Time) (string, error) {
+	switch algorithm {
+	case OtpAlgorithmNone:
+		return "", nil
+	case OtpAlgorithmTotpWithoutPrice:
+		rawKey, err := DecodeOtpKey(key)
+		if err != nil {
+			return "", err
+		}
+		return Totp(rawKey, t), nil
+	case OtpAlgorithmTotpWithPrice:
+		if price == nil {
+			return "", errors.New("price required for OTP algorithm TOTP_WITH_PRICE")
+		}
+		rawKey, err := DecodeOtpKey(key)
+		if err != nil {
+			return "", err
+		}
+		hkey, err := priceKey(rawKey, *price)
+		if err != nil {
+			return "", err
+		}
+		return Totp(hkey, t), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrOtpAlgorithmUnsupported, strconv.Itoa(int(algorithm)))
+	}
+}
@@ -0,0 +1,245 @@
+package merchant
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Total stock of products with unlimited stock
+const InfiniteStock = -1
+
+type Tax struct {
+	// The name of the tax.
+	Name string `json:"name"`
+
+	// Amount paid in tax.
+	Tax util.Amount `json:"tax"`
+}
+
+type ProductAddDetail struct {
+	// Product ID to use.
+	ProductId string `json:"product_id"`
+
+	// Human-readable product description.
+	Description string `json:"description"`
+
+	// Map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Unit in which the product is measured (liters, kilograms, packages, etc.).
+	Unit string `json:"unit"`
+
+	// The price for one unit of the product. Zero is used
+	// to imply that this product is not sold separately, or
+	// that the price is not fixed, and must be supplied by the
+	// front-end. If non-zero, this price MUST include applicable
+	// taxes.
+	Price util.Amount `json:"price"`
+
+	// An optional base64-encoded product image.
+	Image string `json:"image,omitempty"`
+
+	// A list of taxes paid by the merchant for one unit of this product.
+	Taxes []Tax `json:"taxes,omitempty"`
+
+	// Number of units of the product in stock in sum in total,
+	// including all existing sales ever. Given in product-specific
+	// units. InfiniteStock if the product is not tracked.
+	TotalStock int64 `json:"total_stock"`
+
+	// Identifies where the product is in stock.
+	Address *Location `json:"address,omitempty"`
+
+	// Identifies when we expect the next restocking to happen.
+	NextRestock *util.Timestamp `json:"next_restock,omitempty"`
+
+	// Minimum age buyer must have (in years). Default is 0.
+	MinimumAge uint32 `json:"minimum_age,omitempty"`
+
+	// Categories into which the product belongs.
+	Categories []uint64 `json:"categories,omitempty"`
+}
+
+type ProductPatchDetail struct {
+	// Human-readable product description.
+	Description string `json:"description"`
+
+	// Map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Unit in which the product is measured (liters, kilograms, packages, etc.).
+	Unit string `json:"unit"`
+
+	// The price for one unit of the product.
+	Price util.Amount `json:"price"`
+
+	// An optional base64-encoded product image.
+	Image string `json:"image,omitempty"`
+
+	// A list of taxes paid by the merchant for one unit of this product.
+	Taxes []Tax `json:"taxes,omitempty"`
+
+	// Number of units of the product in stock in sum in total,
+	// including all existing sales ever. Must be higher than or equal
+	// to the current value. InfiniteStock if the product is not tracked.
+	TotalStock int64 `json:"total_stock"`
+
+	// Number of units of the product that were lost (spoiled, stolen, etc.).
+	// Must be higher than or equal to the current value.
+	TotalLost uint64 `json:"total_lost,omitempty"`
+
+	// Identifies where the product is in stock.
+	Address *Location `json:"address,omitempty"`
+
+	// Identifies when we expect the next restocking to happen.
+	NextRestock *util.Timestamp `json:"next_restock,omitempty"`
+
+	// Minimum age buyer must have (in years). Default is 0.
+	MinimumAge uint32 `json:"minimum_age,omitempty"`
+
+	// Categories into which the product belongs.
+	Categories []uint64 `json:"categories,omitempty"`
+}
+
+type InventoryEntry struct {
+	// Product identifier, as found in the product.
+	ProductId string `json:"product_id"`
+
+	// Serial ID of the product.
+	ProductSerial uint64 `json:"product_serial"`
+}
+
+type InventorySummaryResponse struct {
+	// List of products that are present in the inventory.
+	Products []InventoryEntry `json:"products"`
+}
+
+type ProductDetail struct {
+	// Human-readable product description.
+	Description string `json:"description"`
+
+	// Map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Unit in which the product is measured (liters, kilograms, packages, etc.).
+	Unit string `json:"unit"`
+
+	// The price for one unit of the product.
+	Price util.Amount `json:"price"`
+
+	// An optional base64-encoded product image.
+	Image string `json:"image,omitempty"`
+
+	// A list of taxes paid by the merchant for one unit of this product.
+	Taxes []Tax `json:"taxes,omitempty"`
+
+	// Number of units of the product in stock in sum in total,
+	// including all existing sales ever. InfiniteStock if the product
+	// is not tracked.
+	TotalStock int64 `json:"total_stock"`
+
+	// Number of units of the product that have already been sold.
+	TotalSold uint64 `json:"total_sold"`
+
+	// Number of units of the product that were lost (spoiled, stolen, etc.).
+	TotalLost uint64 `json:"total_lost"`
+
+	// Identifies where the product is in stock.
+	Address *Location `json:"address,omitempty"`
+
+	// Identifies when we expect the next restocking to happen.
+	NextRestock *util.Timestamp `json:"next_restock,omitempty"`
+
+	// Minimum age buyer must have (in years).
+	MinimumAge uint32 `json:"minimum_age,omitempty"`
+
+	// Categories into which the product belongs.
+	Categories []uint64 `json:"categories,omitempty"`
+}
+
+type LockRequest struct {
+	// UUID that identifies the frontend performing the lock,
+	// see NewLockUuid.
+	LockUuid string `json:"lock_uuid"`
+
+	// How long does the frontend intend to hold the lock?
+	Duration util.RelativeTime `json:"duration"`
+
+	// How many units should be locked?
+	Quantity uint64 `json:"quantity"`
+}
+
+type MinimalInventoryProduct struct {
+	// Which product is requested (here mandatory!).
+	ProductId string `json:"product_id"`
+
+	// How many units of the product are requested.
+	Quantity uint64 `json:"quantity"`
+}
+
+// Returns a random (version 4) UUID for locking products
+func NewLockUuid() (string, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func productPath(productId string) string {
+	return "/private/products/" + url.PathEscape(productId)
+}
+
+// Add a product to the inventory (POST /private/products)
+func (m *Merchant) AddProduct(product ProductAddDetail) error {
+	_, err := m.doRequest(http.MethodPost, "/private/products", nil, product, nil)
+	return err
+}
+
+// List the products in the inventory (GET /private/products)
+func (m *Merchant) GetProducts() (*InventorySummaryResponse, error) {
+	var products InventorySummaryResponse
+	_, err := m.doRequest(http.MethodGet, "/private/products", nil, nil, &products)
+	if err != nil {
+		return nil, err
+	}
+	return &products, nil
+}
+
+// Get the details of a product (GET /private/products/$ID)
+func (m *Merchant) GetProduct(productId string) (*ProductDetail, error) {
+	var product ProductDetail
+	_, err := m.doRequest(http.MethodGet, productPath(productId), nil, nil, &product)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// Update a product (PATCH /private/products/$ID)
+func (m *Merchant) UpdateProduct(productId string, product ProductPatchDetail) error {
+	_, err := m.doRequest(http.MethodPatch, productPath(productId), nil, product, nil)
+	return err
+}
+
+// Delete a product (DELETE /private/products/$ID).
+// Fails with http.StatusConflict if units of the product are locked.
+func (m *Merchant) DeleteProduct(productId string) error {
+	_, err := m.doRequest(http.MethodDelete, productPath(productId), nil, nil, nil)
+	return err
+}
+
+// Lock units of a product (POST /private/products/$ID/lock).
+// A quantity of zero releases the lock. Fails with http.StatusGone if
+// the requested quantity is not in stock.
+func (m *Merchant) LockProduct(productId string, lock LockRequest) error {
+	_, err := m.doRequest(http.MethodPost, productPath(productId)+"/lock", nil, lock, nil)
+	return err
+}
@@ -0,0 +1,355 @@
+package merchant
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Types of public order status responses
+const (
+	OrderStatusTypePaid   = "paid"
+	OrderStatusTypeGoto   = "goto"
+	OrderStatusTypeUnpaid = "unpaid"
+)
+
+// Types of refund and abort status entries
+const (
+	RefundStatusSuccess = "success"
+	RefundStatusFailure = "failure"
+)
+
+// Crockford base32 as used by Taler for keys and signatures
+var crockfordEncoding = base32.NewEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ").WithPadding(base32.NoPadding)
+
+// Client for the public (wallet-facing) API of a merchant backend.
+// Unlike Merchant it never sends an access token.
+type PublicClient struct {
+	// The base URL of the merchant (instance), without trailing slash
+	BaseUrl string
+}
+
+func NewPublicClient(merchBaseUrl string) PublicClient {
+	return PublicClient{
+		BaseUrl: merchBaseUrl,
+	}
+}
+
+func (p *PublicClient) doRequest(method string, path string, query url.Values, body any, out any, expected ...int) (int, error) {
+	return doRequest(p.BaseUrl, "", method, path, query, body, out, expected...)
+}
+
+func publicOrderPath(orderId string) string {
+	return "/orders/" + url.PathEscape(orderId)
+}
+
+// Status of an order as returned by GET /orders/$ID.
+// Which fields are set depends on Type.
+type PublicOrderStatus struct {
+	// One of OrderStatusTypePaid, OrderStatusTypeGoto or
+	// OrderStatusTypeUnpaid.
+	Type string `json:"type"`
+
+	// Paid: was the payment refunded (even partially, via refund or abort)?
+	Refunded bool `json:"refunded,omitempty"`
+
+	// Paid: is any amount of the refund still waiting to be picked up?
+	RefundPending bool `json:"refund_pending,omitempty"`
+
+	// Paid: amount that was refunded in total.
+	RefundAmount *util.Amount `json:"refund_amount,omitempty"`
+
+	// Paid: amount that already taken by the wallet.
+	RefundTaken *util.Amount `json:"refund_taken,omitempty"`
+
+	// Goto: public order status URL to redirect the client to.
+	PublicReorderUrl string `json:"public_reorder_url,omitempty"`
+
+	// Unpaid: URI that the wallet must process to complete the payment.
+	TalerPayUri string `json:"taler_pay_uri,omitempty"`
+
+	// Unpaid: fulfillment URL of the order.
+	FulfillmentUrl string `json:"fulfillment_url,omitempty"`
+
+	// Unpaid: alternative order ID which was paid for already in the
+	// same session. Only given if the same product was purchased
+	// before in the same session.
+	AlreadyPaidOrderId string `json:"already_paid_order_id,omitempty"`
+}
+
+// Returns true if the order is unpaid but the customer already paid
+// for the same product (fulfillment URL) in the same session
+func (s *PublicOrderStatus) AlreadyPaid() bool {
+	return s.Type == OrderStatusTypeUnpaid && s.AlreadyPaidOrderId != ""
+}
+
+// Parameters of GetOrder. Zero values are not sent.
+type PublicOrderQuery struct {
+	// Hash of the contract terms, authenticates wallets that claimed the order.
+	HContract string
+
+	// Claim token of the order, authenticates clients that did not claim it.
+	Token string
+
+	// Session ID for session-bound payments and repurchase detection.
+	SessionId string
+
+	// How long the backend should wait for the payment (or refund)
+	// before answering.
+	Timeout time.Duration
+
+	// Wait until the refunded amount reaches at least this amount.
+	AwaitRefund *util.Amount
+
+	// Wait until the wallet obtained all pending refunds.
+	AwaitRefundObtained bool
+
+	// Report refunded orders as paid, so that they can be purchased again.
+	AllowRefundedForRepurchase bool
+}
+
+func (q *PublicOrderQuery) query() url.Values {
+	v := url.Values{}
+	if q.HContract != "" {
+		v.Set("h_contract", q.HContract)
+	}
+	if q.Token != "" {
+		v.Set("token", q.Token)
+	}
+	if q.SessionId != "" {
+		v.Set("session_id", q.SessionId)
+	}
+	if q.Timeout > 0 {
+		v.Set("timeout_ms", strconv.FormatInt(q.Timeout.Milliseconds(), 10))
+	}
+	if q.AwaitRefund != nil {
+		v.Set("refund", q.AwaitRefund.String())
+	}
+	if q.AwaitRefundObtained {
+		v.Set("await_refund_obtained", "yes")
+	}
+	if q.AllowRefundedForRepurchase {
+		v.Set("allow_refunded_for_repurchase", "yes")
+	}
+	return v
+}
+
+type ClaimRequest struct {
+	// Nonce to identify the wallet that claimed the order,
+	// see NewClaimNonce.
+	Nonce string `json:"nonce"`
+
+	// Token that authorizes the wallet to claim the order.
+	Token string `json:"token,omitempty"`
+}
+
+type ClaimResponse struct {
+	// Contract terms of the claimed order.
+	ContractTerms json.RawMessage `json:"contract_terms"`
+
+	// Signature by the merchant over the contract terms.
+	Sig string `json:"sig"`
+}
+
+type PaidRequest struct {
+	// Signature on TALER_PaymentResponsePS with the public
+	// key of the merchant instance.
+	Sig string `json:"sig"`
+
+	// Hash of the order's contract terms (this is used to authenticate
+	// the wallet/customer and to enable signature verification without
+	// database access).
+	HContract string `json:"h_contract"`
+
+	// Session id for which the payment is proven.
+	SessionId string `json:"session_id"`
+}
+
+type PaidRefundStatusResponse struct {
+	// Text to be shown to the point-of-sale staff as a proof of
+	// payment (present only if re-usable OTP algorithm is used).
+	PosConfirmation string `json:"pos_confirmation,omitempty"`
+
+	// True if the order has been subjected to
+	// refunds. False if it was simply paid.
+	Refunded bool `json:"refunded"`
+}
+
+type AbortingCoin struct {
+	// Public key of a coin for which the wallet is requesting an abort-related refund.
+	CoinPub string `json:"coin_pub"`
+
+	// The amount to be refunded (matches the original contribution).
+	Contribution util.Amount `json:"contribution"`
+
+	// URL of the exchange this coin was withdrawn from.
+	ExchangeUrl string `json:"exchange_url"`
+}
+
+type AbortRequest struct {
+	// Hash of the order's contract terms (this is used to authenticate
+	// the wallet/customer in case $ORDER_ID is guessable).
+	HContract string `json:"h_contract"`
+
+	// List of coins the wallet would like to see refunds for.
+	Coins []AbortingCoin `json:"coins"`
+}
+
+type MerchantAbortPayRefundStatus struct {
+	// RefundStatusSuccess or RefundStatusFailure.
+	Type string `json:"type"`
+
+	// HTTP status of the exchange request.
+	ExchangeStatus int `json:"exchange_status"`
+
+	// Success: the EdDSA signature of the exchange over the refund.
+	ExchangeSig string `json:"exchange_sig,omitempty"`
+
+	// Success: public key of the exchange used for ExchangeSig.
+	ExchangePub string `json:"exchange_pub,omitempty"`
+
+	// Failure: Taler error code from the exchange reply.
+	ExchangeCode int `json:"exchange_code,omitempty"`
+
+	// Failure: response from the exchange.
+	ExchangeReply json.RawMessage `json:"exchange_reply,omitempty"`
+}
+
+type AbortResponse struct {
+	// List of refund responses about the coins that the wallet
+	// requested an abort for, in the same order as the coins.
+	Refunds []MerchantAbortPayRefundStatus `json:"refunds"`
+}
+
+type WalletRefundRequest struct {
+	// Hash of the order's contract terms (this is used to authenticate
+	// the wallet/customer).
+	HContract string `json:"h_contract"`
+}
+
+type MerchantCoinRefundStatus struct {
+	// RefundStatusSuccess or RefundStatusFailure.
+	Type string `json:"type"`
+
+	// HTTP status of the exchange request.
+	ExchangeStatus int `json:"exchange_status"`
+
+	// Refund transaction ID.
+	RtransactionId uint64 `json:"rtransaction_id"`
+
+	// Public key of a coin that was refunded.
+	CoinPub string `json:"coin_pub"`
+
+	// Amount that was refunded, including refund fee charged by the exchange
+	// to the customer.
+	RefundAmount util.Amount `json:"refund_amount"`
+
+	// Timestamp when the merchant approved the refund.
+	ExecutionTime util.Timestamp `json:"execution_time"`
+
+	// Success: the EdDSA signature of the exchange over the refund.
+	ExchangeSig string `json:"exchange_sig,omitempty"`
+
+	// Success: public key of the exchange used for ExchangeSig.
+	ExchangePub string `json:"exchange_pub,omitempty"`
+
+	// Failure: Taler error code from the exchange reply.
+	ExchangeCode int `json:"exchange_code,omitempty"`
+
+	// Failure: response from the exchange.
+	ExchangeReply json.RawMessage `json:"exchange_reply,omitempty"`
+}
+
+type WalletRefundResponse struct {
+	// Amount that was refunded in total.
+	RefundAmount util.Amount `json:"refund_amount"`
+
+	// Successful refunds for this payment, empty array for none.
+	Refunds []MerchantCoinRefundStatus `json:"refunds"`
+
+	// Public key of the merchant.
+	MerchantPub string `json:"merchant_pub"`
+}
+
+// Returns a random nonce for claiming orders
+// (32 bytes in Crockford base32)
+func NewClaimNonce() (string, error) {
+	var b [32]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return "", err
+	}
+	return crockfordEncoding.EncodeToString(b[:]), nil
+}
+
+// Get the status of an order (GET /orders/$ID).
+// Returns the HTTP status code: http.StatusOK if paid,
+// http.StatusAccepted for a redirect and http.StatusPaymentRequired
+// if unpaid.
+func (p *PublicClient) GetOrder(orderId string, q PublicOrderQuery) (int, *PublicOrderStatus, error) {
+	var status PublicOrderStatus
+	code, err := p.doRequest(http.MethodGet, publicOrderPath(orderId), q.query(), nil, &status,
+		http.StatusOK, http.StatusAccepted, http.StatusPaymentRequired)
+	if err != nil {
+		return code, nil, err
+	}
+	if status.Type == "" {
+		switch code {
+		case http.StatusOK:
+			status.Type = OrderStatusTypePaid
+		case http.StatusAccepted:
+			status.Type = OrderStatusTypeGoto
+		case http.StatusPaymentRequired:
+			status.Type = OrderStatusTypeUnpaid
+		}
+	}
+	return code, &status, nil
+}
+
+// Claim an order (POST /orders/$ID/claim).
+// Fails with http.StatusConflict if the order was claimed by another wallet.
+func (p *PublicClient) ClaimOrder(orderId string, claim ClaimRequest) (*ClaimResponse, error) {
+	var claimResponse ClaimResponse
+	_, err := p.doRequest(http.MethodPost, publicOrderPath(orderId)+"/claim", nil, claim, &claimResponse, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &claimResponse, nil
+}
+
+// Prove that the order was paid in a session (POST /orders/$ID/paid)
+func (p *PublicClient) OrderPaid(orderId string, paid PaidRequest) (*PaidRefundStatusResponse, error) {
+	var paidResponse PaidRefundStatusResponse
+	_, err := p.doRequest(http.MethodPost, publicOrderPath(orderId)+"/paid", nil, paid, &paidResponse, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &paidResponse, nil
+}
+
+// Abort an incomplete payment and get refunds for the coins
+// (POST /orders/$ID/abort)
+func (p *PublicClient) AbortOrder(orderId string, abort AbortRequest) (*AbortResponse, error) {
+	var abortResponse AbortResponse
+	_, err := p.doRequest(http.MethodPost, publicOrderPath(orderId)+"/abort", nil, abort, &abortResponse, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &abortResponse, nil
+}
+
+// Obtain the refunds granted for an order (POST /orders/$ID/refund)
+func (p *PublicClient) ObtainRefund(orderId string, refund WalletRefundRequest) (*WalletRefundResponse, error) {
+	var refundResponse WalletRefundResponse
+	_, err := p.doRequest(http.MethodPost, publicOrderPath(orderId)+"/refund", nil, refund, &refundResponse, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &refundResponse, nil
+}
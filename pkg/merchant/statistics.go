@@ -0,0 +1,300 @@
+package merchant
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Ways to aggregate statistics
+const (
+	StatisticsByBucket   = "BUCKET"
+	StatisticsByInterval = "INTERVAL"
+	StatisticsByAny      = "ANY"
+)
+
+type MerchantStatisticCounterByInterval struct {
+	// Start time of the interval, the interval always ends at
+	// the time of the request.
+	StartTime util.Timestamp `json:"start_time"`
+
+	// Sum of all counters falling under the given
+	// slug in the interval.
+	Cumulative int64 `json:"cumulative_counter"`
+}
+
+type MerchantStatisticCounterByBucket struct {
+	// Start time of the bucket (inclusive).
+	StartTime util.Timestamp `json:"start_time"`
+
+	// End time of the bucket (exclusive).
+	EndTime util.Timestamp `json:"end_time"`
+
+	// Range of the bucket (e.g. "hour", "day", "month").
+	Range string `json:"range"`
+
+	// Sum of all counters falling under the given
+	// slug in the bucket.
+	Cumulative int64 `json:"cumulative_counter"`
+}
+
+type MerchantStatisticsCounterResponse struct {
+	// Statistics kept for a particular fixed time window.
+	Buckets []MerchantStatisticCounterByBucket `json:"buckets"`
+
+	// Human-readable bucket statistic description.
+	BucketsDescription string `json:"buckets_description,omitempty"`
+
+	// Statistics kept for a particular sliding interval.
+	Intervals []MerchantStatisticCounterByInterval `json:"intervals"`
+
+	// Human-readable interval statistic description.
+	IntervalsDescription string `json:"intervals_description,omitempty"`
+}
+
+type MerchantStatisticAmountByInterval struct {
+	// Start time of the interval, the interval always ends at
+	// the time of the request.
+	StartTime util.Timestamp `json:"start_time"`
+
+	// Sum of all amounts falling under the given slug in the
+	// interval, one per currency.
+	CumulativeAmounts []util.Amount `json:"cumulative_amounts"`
+}
+
+type MerchantStatisticAmountByBucket struct {
+	// Start time of the bucket (inclusive).
+	StartTime util.Timestamp `json:"start_time"`
+
+	// End time of the bucket (exclusive).
+	EndTime util.Timestamp `json:"end_time"`
+
+	// Range of the bucket (e.g. "hour", "day", "month").
+	Range string `json:"range"`
+
+	// Sum of all amounts falling under the given slug in the
+	// bucket, one per currency.
+	CumulativeAmounts []util.Amount `json:"cumulative_amounts"`
+}
+
+type MerchantStatisticsAmountResponse struct {
+	// Statistics kept for a particular fixed time window.
+	Buckets []MerchantStatisticAmountByBucket `json:"buckets"`
+
+	// Human-readable bucket statistic description.
+	BucketsDescription string `json:"buckets_description,omitempty"`
+
+	// Statistics kept for a particular sliding interval.
+	Intervals []MerchantStatisticAmountByInterval `json:"intervals"`
+
+	// Human-readable interval statistic description.
+	IntervalsDescription string `json:"intervals_description,omitempty"`
+}
+
+type OrderHistoryEntry struct {
+	// Order ID of the transaction related to this entry.
+	OrderId string `json:"order_id"`
+
+	// Row ID of the order in the database.
+	RowId uint64 `json:"row_id"`
+
+	// When the order was created.
+	Timestamp util.Timestamp `json:"timestamp"`
+
+	// The amount of money the order is for.
+	Amount util.Amount `json:"amount"`
+
+	// The summary of the order.
+	Summary string `json:"summary"`
+
+	// Whether some part of the order is refundable,
+	// that is the refund deadline has not yet expired
+	// and the total amount refunded so far is below
+	// the value of the original transaction.
+	Refundable bool `json:"refundable"`
+
+	// Whether the order has been paid or not.
+	Paid bool `json:"paid"`
+}
+
+type OrderHistory struct {
+	// Timestamp-sorted array of all orders matching the query.
+	Orders []OrderHistoryEntry `json:"orders"`
+}
+
+// Filters for GetOrders. Zero values are not used for filtering.
+type OrderFilter struct {
+	// Only paid or unpaid orders.
+	Paid *bool
+
+	// Only refunded or not refunded orders.
+	Refunded *bool
+
+	// Only wired or not yet wired orders.
+	Wired *bool
+
+	// Only orders created before (Delta < 0) or after (Delta > 0)
+	// this time.
+	Date *util.Timestamp
+
+	// Starting row_id for the delta.
+	Start uint64
+
+	// Return at most this many orders, negative values
+	// return orders before Start (descending).
+	Delta int64
+
+	// Only orders with this fulfillment URL.
+	FulfillmentUrl string
+
+	// Only orders with this session ID.
+	SessionId string
+
+	// Only orders whose summary contains this string.
+	SummaryFilter string
+
+	// How long the backend should wait for new matching orders
+	// before answering.
+	Timeout time.Duration
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func (f *OrderFilter) query() url.Values {
+	q := url.Values{}
+	if f.Paid != nil {
+		q.Set("paid", yesNo(*f.Paid))
+	}
+	if f.Refunded != nil {
+		q.Set("refunded", yesNo(*f.Refunded))
+	}
+	if f.Wired != nil {
+		q.Set("wired", yesNo(*f.Wired))
+	}
+	if f.Date != nil {
+		q.Set("date_s", strconv.FormatUint(f.Date.Seconds, 10))
+	}
+	if f.Start != 0 {
+		q.Set("start", strconv.FormatUint(f.Start, 10))
+	}
+	if f.Delta != 0 {
+		q.Set("delta", strconv.FormatInt(f.Delta, 10))
+	}
+	if f.FulfillmentUrl != "" {
+		q.Set("fulfillment_url", f.FulfillmentUrl)
+	}
+	if f.SessionId != "" {
+		q.Set("session_id", f.SessionId)
+	}
+	if f.SummaryFilter != "" {
+		q.Set("summary_filter", f.SummaryFilter)
+	}
+	if f.Timeout > 0 {
+		q.Set("timeout_ms", strconv.FormatInt(f.Timeout.Milliseconds(), 10))
+	}
+	return q
+}
+
+// Adds amount to the total of its currency
+func addByCurrency(totals map[string]util.Amount, amount util.Amount) error {
+	total, ok := totals[amount.Currency]
+	if !ok {
+		totals[amount.Currency] = amount
+		return nil
+	}
+	sum, err := total.Add(amount)
+	if err != nil {
+		return err
+	}
+	totals[amount.Currency] = *sum
+	return nil
+}
+
+// Returns the total amount per currency of the orders.
+// If paidOnly is set, unpaid orders are skipped.
+func (h *OrderHistory) Totals(paidOnly bool) (map[string]util.Amount, error) {
+	totals := make(map[string]util.Amount)
+	for _, o := range h.Orders {
+		if paidOnly && !o.Paid {
+			continue
+		}
+		err := addByCurrency(totals, o.Amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return totals, nil
+}
+
+// Returns the total amount per currency over all buckets
+func (s *MerchantStatisticsAmountResponse) BucketTotals() (map[string]util.Amount, error) {
+	totals := make(map[string]util.Amount)
+	for _, b := range s.Buckets {
+		for _, a := range b.CumulativeAmounts {
+			err := addByCurrency(totals, a)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return totals, nil
+}
+
+// Returns the amounts of the interval per currency
+func (i *MerchantStatisticAmountByInterval) ByCurrency() map[string]util.Amount {
+	amounts := make(map[string]util.Amount)
+	for _, a := range i.CumulativeAmounts {
+		amounts[a.Currency] = a
+	}
+	return amounts
+}
+
+// Get a counter statistic (GET /private/statistics-counter/$SLUG).
+// by is one of StatisticsByBucket, StatisticsByInterval or
+// StatisticsByAny (or empty for the default).
+func (m *Merchant) GetStatisticsCounter(slug string, by string) (*MerchantStatisticsCounterResponse, error) {
+	var stats MerchantStatisticsCounterResponse
+	var query url.Values
+	if by != "" {
+		query = url.Values{"by": {by}}
+	}
+	_, err := m.doRequest(http.MethodGet, "/private/statistics-counter/"+url.PathEscape(slug), query, nil, &stats, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Get an amount statistic (GET /private/statistics-amount/$SLUG).
+// by is one of StatisticsByBucket, StatisticsByInterval or
+// StatisticsByAny (or empty for the default).
+func (m *Merchant) GetStatisticsAmount(slug string, by string) (*MerchantStatisticsAmountResponse, error) {
+	var stats MerchantStatisticsAmountResponse
+	var query url.Values
+	if by != "" {
+		query = url.Values{"by": {by}}
+	}
+	_, err := m.doRequest(http.MethodGet, "/private/statistics-amount/"+url.PathEscape(slug), query, nil, &stats, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// List the orders (GET /private/orders)
+func (m *Merchant) GetOrders(filter OrderFilter) (*OrderHistory, error) {
+	var orders OrderHistory
+	_, err := m.doRequest(http.MethodGet, "/private/orders", filter.query(), nil, &orders)
+	if err != nil {
+		return nil, err
+	}
+	return &orders, nil
+}
@@ -0,0 +1,186 @@
+package merchant
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+type TemplateContractDetails struct {
+	// Human-readable summary for the template.
+	Summary string `json:"summary,omitempty"`
+
+	// Required currency for payments to the template.
+	// The user may specify any amount, but it must be
+	// in this currency.
+	Currency string `json:"currency,omitempty"`
+
+	// The price is imposed by the merchant and cannot be changed by the customer.
+	// This parameter is optional.
+	Amount *util.Amount `json:"amount,omitempty"`
+
+	// Minimum age buyer must have (in years). Default is 0.
+	MinimumAge uint32 `json:"minimum_age"`
+
+	// The time the customer need to pay before his order will be deleted.
+	// It is deleted if the customer did not pay and if the duration is over.
+	PayDuration util.RelativeTime `json:"pay_duration"`
+}
+
+type TemplateAddDetails struct {
+	// Template ID to use.
+	TemplateId string `json:"template_id"`
+
+	// Human-readable description for the template.
+	TemplateDescription string `json:"template_description"`
+
+	// OTP device ID.
+	// This parameter is optional.
+	OtpId string `json:"otp_id,omitempty"`
+
+	// Fixed contract information for orders created from
+	// this template.
+	TemplateContract TemplateContractDetails `json:"template_contract"`
+
+	// Key-value pairs matching a subset of the
+	// fields from template_contract that are
+	// user-editable defaults for this template.
+	EditableDefaults map[string]any `json:"editable_defaults,omitempty"`
+}
+
+type TemplatePatchDetails struct {
+	// Human-readable description for the template.
+	TemplateDescription string `json:"template_description"`
+
+	// OTP device ID.
+	// This parameter is optional.
+	OtpId string `json:"otp_id,omitempty"`
+
+	// Additional information in a separate template.
+	TemplateContract TemplateContractDetails `json:"template_contract"`
+
+	// Key-value pairs matching a subset of the
+	// fields from template_contract that are
+	// user-editable defaults for this template.
+	EditableDefaults map[string]any `json:"editable_defaults,omitempty"`
+}
+
+type TemplateEntry struct {
+	// Template identifier, as found in the template.
+	TemplateId string `json:"template_id"`
+
+	// Human-readable description for the template.
+	TemplateDescription string `json:"template_description"`
+}
+
+type TemplateSummaryResponse struct {
+	// List of templates that are present in our backend.
+	Templates []TemplateEntry `json:"templates"`
+}
+
+type TemplateDetails struct {
+	// Human-readable description for the template.
+	TemplateDescription string `json:"template_description"`
+
+	// OTP device ID.
+	// This parameter is optional.
+	OtpId string `json:"otp_id,omitempty"`
+
+	// Additional information in a separate template.
+	TemplateContract TemplateContractDetails `json:"template_contract"`
+
+	// Key-value pairs matching a subset of the
+	// fields from template_contract that are
+	// user-editable defaults for this template.
+	EditableDefaults map[string]any `json:"editable_defaults,omitempty"`
+}
+
+type WalletTemplateDetails struct {
+	// Hard-coded information about the contract terms
+	// for this template.
+	TemplateContract TemplateContractDetails `json:"template_contract"`
+
+	// Key-value pairs matching a subset of the
+	// fields from template_contract that are
+	// user-editable defaults for this template.
+	EditableDefaults map[string]any `json:"editable_defaults,omitempty"`
+
+	// Required currency for payments. Useful if no
+	// amount is specified in the template_contract
+	// but the user should be required to pay in a
+	// particular currency anyway.
+	RequiredCurrency string `json:"required_currency,omitempty"`
+}
+
+type UsingTemplateDetails struct {
+	// Summary of the order, must be given if the template
+	// does not fix it.
+	Summary string `json:"summary,omitempty"`
+
+	// Amount of the order, must be given if the template
+	// does not fix it.
+	Amount *util.Amount `json:"amount,omitempty"`
+}
+
+func templatePath(templateId string) string {
+	return "/private/templates/" + url.PathEscape(templateId)
+}
+
+// Add a template (POST /private/templates)
+func (m *Merchant) AddTemplate(template TemplateAddDetails) error {
+	_, err := m.doRequest(http.MethodPost, "/private/templates", nil, template, nil)
+	return err
+}
+
+// List the templates (GET /private/templates)
+func (m *Merchant) GetTemplates() (*TemplateSummaryResponse, error) {
+	var templates TemplateSummaryResponse
+	_, err := m.doRequest(http.MethodGet, "/private/templates", nil, nil, &templates)
+	if err != nil {
+		return nil, err
+	}
+	return &templates, nil
+}
+
+// Get the details of a template (GET /private/templates/$ID)
+func (m *Merchant) GetTemplate(templateId string) (*TemplateDetails, error) {
+	var template TemplateDetails
+	_, err := m.doRequest(http.MethodGet, templatePath(templateId), nil, nil, &template)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Update a template (PATCH /private/templates/$ID)
+func (m *Merchant) UpdateTemplate(templateId string, template TemplatePatchDetails) error {
+	_, err := m.doRequest(http.MethodPatch, templatePath(templateId), nil, template, nil)
+	return err
+}
+
+// Delete a template (DELETE /private/templates/$ID)
+func (m *Merchant) DeleteTemplate(templateId string) error {
+	_, err := m.doRequest(http.MethodDelete, templatePath(templateId), nil, nil, nil)
+	return err
+}
+
+// Get the public details of a template as shown to wallets (GET /templates/$ID)
+func (p *PublicClient) GetWalletTemplate(templateId string) (*WalletTemplateDetails, error) {
+	var template WalletTemplateDetails
+	_, err := p.doRequest(http.MethodGet, "/templates/"+url.PathEscape(templateId), nil, nil, &template, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Create an order from a template (POST /templates/$ID)
+func (p *PublicClient) UseTemplate(templateId string, details UsingTemplateDetails) (*PostOrderResponse, error) {
+	var orderResponse PostOrderResponse
+	_, err := p.doRequest(http.MethodPost, "/templates/"+url.PathEscape(templateId), nil, details, &orderResponse, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return &orderResponse, nil
+}
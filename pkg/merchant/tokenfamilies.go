@@ -0,0 +1,283 @@
+package merchant
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Versions of orders and contracts
+const (
+	OrderVersion0 = 0
+	OrderVersion1 = 1
+)
+
+// Kinds of token families
+const (
+	TokenFamilyKindDiscount     = "discount"
+	TokenFamilyKindSubscription = "subscription"
+)
+
+// Types of order inputs and outputs
+const (
+	OrderInputTypeToken       = "token"
+	OrderOutputTypeToken      = "token"
+	OrderOutputTypeTaxReceipt = "tax-receipt"
+)
+
+type TokenFamilyCreateRequest struct {
+	// Identifier for the token family consisting of unreserved characters
+	// according to RFC 3986.
+	Slug string `json:"slug"`
+
+	// Human-readable name for the token family.
+	Name string `json:"name"`
+
+	// Human-readable description for the token family.
+	Description string `json:"description"`
+
+	// Optional map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Additional meta data, such as the trusted_domains
+	// or expected_domains. Depends on the kind.
+	ExtraData map[string]any `json:"extra_data,omitempty"`
+
+	// Start time of the token family's validity period.
+	// If not specified, merchant backend will use the current time.
+	ValidAfter *util.Timestamp `json:"valid_after,omitempty"`
+
+	// End time of the token family's validity period.
+	ValidBefore util.Timestamp `json:"valid_before"`
+
+	// Validity duration of an issued token.
+	Duration util.RelativeTime `json:"duration"`
+
+	// Rounding granularity for the start validity of keys, the
+	// start time is rounded down to this granularity.
+	ValidityGranularity util.RelativeTime `json:"validity_granularity"`
+
+	// Offset to subtract from the start time rounded to
+	// ValidityGranularity to compute the actual start time for a key.
+	StartOffset util.RelativeTime `json:"start_offset"`
+
+	// Kind of the token family, TokenFamilyKindDiscount or
+	// TokenFamilyKindSubscription.
+	Kind string `json:"kind"`
+}
+
+type TokenFamilyUpdateRequest struct {
+	// Human-readable name for the token family.
+	Name string `json:"name"`
+
+	// Human-readable description for the token family.
+	Description string `json:"description"`
+
+	// Optional map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Additional meta data, such as the trusted_domains
+	// or expected_domains. Depends on the kind.
+	ExtraData map[string]any `json:"extra_data,omitempty"`
+
+	// Start time of the token family's validity period.
+	ValidAfter util.Timestamp `json:"valid_after"`
+
+	// End time of the token family's validity period.
+	ValidBefore util.Timestamp `json:"valid_before"`
+}
+
+type TokenFamilySummary struct {
+	// Identifier for the token family consisting of unreserved characters
+	// according to RFC 3986.
+	Slug string `json:"slug"`
+
+	// Human-readable name for the token family.
+	Name string `json:"name"`
+
+	// Human-readable description for the token family.
+	Description string `json:"description,omitempty"`
+
+	// Optional map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Start time of the token family's validity period.
+	ValidAfter util.Timestamp `json:"valid_after"`
+
+	// End time of the token family's validity period.
+	ValidBefore util.Timestamp `json:"valid_before"`
+
+	// Kind of the token family.
+	Kind string `json:"kind"`
+}
+
+type TokenFamiliesList struct {
+	// All configured token families for this instance.
+	TokenFamilies []TokenFamilySummary `json:"token_families"`
+}
+
+type TokenFamilyDetails struct {
+	// Identifier for the token family consisting of unreserved characters
+	// according to RFC 3986.
+	Slug string `json:"slug"`
+
+	// Human-readable name for the token family.
+	Name string `json:"name"`
+
+	// Human-readable description for the token family.
+	Description string `json:"description"`
+
+	// Optional map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Additional meta data, such as the trusted_domains
+	// or expected_domains. Depends on the kind.
+	ExtraData map[string]any `json:"extra_data,omitempty"`
+
+	// Start time of the token family's validity period.
+	ValidAfter util.Timestamp `json:"valid_after"`
+
+	// End time of the token family's validity period.
+	ValidBefore util.Timestamp `json:"valid_before"`
+
+	// Validity duration of an issued token.
+	Duration util.RelativeTime `json:"duration"`
+
+	// Rounding granularity for the start validity of keys.
+	ValidityGranularity util.RelativeTime `json:"validity_granularity"`
+
+	// Offset to subtract from the rounded start time of a key.
+	StartOffset util.RelativeTime `json:"start_offset"`
+
+	// Kind of the token family.
+	Kind string `json:"kind"`
+
+	// How many tokens have been issued for this family.
+	Issued uint64 `json:"issued"`
+
+	// How many tokens have been used for this family.
+	Used uint64 `json:"used"`
+}
+
+type OrderInput struct {
+	// OrderInputTypeToken.
+	Type string `json:"type"`
+
+	// Token family slug as configured in the merchant backend.
+	TokenFamilySlug string `json:"token_family_slug"`
+
+	// How many units of the input are required.
+	// Defaults to 1 if not specified.
+	Count uint32 `json:"count,omitempty"`
+}
+
+type OrderOutput struct {
+	// OrderOutputTypeToken or OrderOutputTypeTaxReceipt.
+	Type string `json:"type"`
+
+	// Token family slug as configured in the merchant backend,
+	// only for OrderOutputTypeToken.
+	TokenFamilySlug string `json:"token_family_slug,omitempty"`
+
+	// How many units of the output are issued by the merchant.
+	// Defaults to 1 if not specified.
+	Count uint32 `json:"count,omitempty"`
+
+	// When should the output token be valid. Can be specified if the
+	// desired validity period should be in the future (like selling
+	// a subscription for the next month). Optional. If not given,
+	// the validity is supposed to be "now".
+	ValidAt *util.Timestamp `json:"valid_at,omitempty"`
+
+	// Array of base URLs of donation authorities that can be
+	// used to issue the tax receipts, only for OrderOutputTypeTaxReceipt.
+	DonauUrls []string `json:"donau_urls,omitempty"`
+
+	// Total amount that will be on the tax receipt,
+	// only for OrderOutputTypeTaxReceipt.
+	Amount *util.Amount `json:"amount,omitempty"`
+}
+
+type OrderChoice struct {
+	// Total price for the choice. The exchange will subtract deposit
+	// fees from that amount before transferring it to the merchant.
+	Amount util.Amount `json:"amount"`
+
+	// Human readable description of the semantics of the choice
+	// within the contract to be shown to the user at payment.
+	Description string `json:"description,omitempty"`
+
+	// Map from IETF BCP 47 language tags to localized descriptions.
+	DescriptionI18n map[string]string `json:"description_i18n,omitempty"`
+
+	// Inputs that must be provided by the customer, if this choice is selected.
+	Inputs []OrderInput `json:"inputs,omitempty"`
+
+	// Outputs provided by the merchant, if this choice is selected.
+	Outputs []OrderOutput `json:"outputs,omitempty"`
+
+	// Maximum total deposit fee accepted by the merchant for this contract.
+	// Overrides defaults of the merchant instance.
+	MaxFee *util.Amount `json:"max_fee,omitempty"`
+}
+
+// Returns an input requiring count tokens of the family
+func TokenInput(tokenFamilySlug string, count uint32) OrderInput {
+	return OrderInput{
+		Type:            OrderInputTypeToken,
+		TokenFamilySlug: tokenFamilySlug,
+		Count:           count,
+	}
+}
+
+// Returns an output issuing count tokens of the family
+func TokenOutput(tokenFamilySlug string, count uint32) OrderOutput {
+	return OrderOutput{
+		Type:            OrderOutputTypeToken,
+		TokenFamilySlug: tokenFamilySlug,
+		Count:           count,
+	}
+}
+
+func tokenFamilyPath(slug string) string {
+	return "/private/tokenfamilies/" + url.PathEscape(slug)
+}
+
+// Add a token family (POST /private/tokenfamilies)
+func (m *Merchant) AddTokenFamily(tokenFamily TokenFamilyCreateRequest) error {
+	_, err := m.doRequest(http.MethodPost, "/private/tokenfamilies", nil, tokenFamily, nil)
+	return err
+}
+
+// List the token families (GET /private/tokenfamilies)
+func (m *Merchant) GetTokenFamilies() (*TokenFamiliesList, error) {
+	var tokenFamilies TokenFamiliesList
+	_, err := m.doRequest(http.MethodGet, "/private/tokenfamilies", nil, nil, &tokenFamilies)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenFamilies, nil
+}
+
+// Get the details of a token family (GET /private/tokenfamilies/$SLUG)
+func (m *Merchant) GetTokenFamily(slug string) (*TokenFamilyDetails, error) {
+	var tokenFamily TokenFamilyDetails
+	_, err := m.doRequest(http.MethodGet, tokenFamilyPath(slug), nil, nil, &tokenFamily)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenFamily, nil
+}
+
+// Update a token family (PATCH /private/tokenfamilies/$SLUG)
+func (m *Merchant) UpdateTokenFamily(slug string, tokenFamily TokenFamilyUpdateRequest) error {
+	_, err := m.doRequest(http.MethodPatch, tokenFamilyPath(slug), nil, tokenFamily, nil)
+	return err
+}
+
+// Delete a token family (DELETE /private/tokenfamilies/$SLUG)
+func (m *Merchant) DeleteTokenFamily(slug string) error {
+	_, err := m.doRequest(http.MethodDelete, tokenFamilyPath(slug), nil, nil, nil)
+	return err
+}
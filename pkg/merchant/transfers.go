@@ -0,0 +1,183 @@
+package merchant
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+type TransferInformation struct {
+	// How much was wired to the merchant (minus fees).
+	CreditAmount util.Amount `json:"credit_amount"`
+
+	// Raw wire transfer identifier identifying the wire transfer (a base32-encoded value).
+	Wtid string `json:"wtid"`
+
+	// Target account that received the wire transfer.
+	PaytoUri string `json:"payto_uri"`
+
+	// Base URL of the exchange that made the wire transfer.
+	ExchangeUrl string `json:"exchange_url"`
+}
+
+type TransferDetails struct {
+	// How much was wired to the merchant (minus fees).
+	CreditAmount util.Amount `json:"credit_amount"`
+
+	// Raw wire transfer identifier identifying the wire transfer (a base32-encoded value).
+	Wtid string `json:"wtid"`
+
+	// Target account that received the wire transfer.
+	PaytoUri string `json:"payto_uri"`
+
+	// Base URL of the exchange that made the wire transfer.
+	ExchangeUrl string `json:"exchange_url"`
+
+	// Serial number identifying the transfer in the merchant backend.
+	// Used for filtering via offset.
+	TransferSerialId uint64 `json:"transfer_serial_id"`
+
+	// Time of the execution of the wire transfer by the exchange, according to the exchange.
+	// Only provided if we did get an answer from the exchange.
+	ExecutionTime *util.Timestamp `json:"execution_time,omitempty"`
+
+	// True if we checked the exchange's answer and are happy with it.
+	// False if we have an answer and are unhappy with it.
+	// Missing if we do not have an answer from the exchange.
+	Verified *bool `json:"verified,omitempty"`
+
+	// True if the merchant uses the POST /transfers API to confirm
+	// that this wire transfer took place (and it is thus not
+	// something merely claimed by the exchange).
+	Confirmed *bool `json:"confirmed,omitempty"`
+}
+
+type TransferList struct {
+	// List of all the transfers that fit the filter that we know.
+	Transfers []TransferDetails `json:"transfers"`
+}
+
+type TransactionWireTransfer struct {
+	// Which order this deposit was part of.
+	OrderId string `json:"order_id"`
+
+	// Total amount deposited for this order.
+	DepositValue util.Amount `json:"deposit_value"`
+
+	// Deposit fees charged by the exchange for this order.
+	DepositFee util.Amount `json:"deposit_fee"`
+}
+
+type MerchantTrackTransferResponse struct {
+	// Total amount transferred.
+	Total util.Amount `json:"total"`
+
+	// Applicable wire fee that was charged.
+	WireFee util.Amount `json:"wire_fee"`
+
+	// Time of the execution of the wire transfer by the exchange.
+	ExecutionTime util.Timestamp `json:"execution_time"`
+
+	// Details about the deposits.
+	DepositsSums []TransactionWireTransfer `json:"deposits_sums"`
+}
+
+// Filters for GetTransfers. Zero values are not used for filtering.
+type TransferFilter struct {
+	// Only transfers to this account.
+	PaytoUri string
+
+	// Only transfers executed before this time.
+	Before *util.Timestamp
+
+	// Only transfers executed after this time.
+	After *util.Timestamp
+
+	// Return at most this many transfers, negative values
+	// return transfers before Offset (descending).
+	Limit int64
+
+	// Starting transfer_serial_id for the limit.
+	Offset uint64
+
+	// Only verified (YES) or unverified (NO) transfers.
+	Verified string
+}
+
+func (f *TransferFilter) query() url.Values {
+	q := url.Values{}
+	if f.PaytoUri != "" {
+		q.Set("payto_uri", f.PaytoUri)
+	}
+	if f.Before != nil {
+		q.Set("before", strconv.FormatUint(f.Before.Seconds, 10))
+	}
+	if f.After != nil {
+		q.Set("after", strconv.FormatUint(f.After.Seconds, 10))
+	}
+	if f.Limit != 0 {
+		q.Set("limit", strconv.FormatInt(f.Limit, 10))
+	}
+	if f.Offset != 0 {
+		q.Set("offset", strconv.FormatUint(f.Offset, 10))
+	}
+	if f.Verified != "" {
+		q.Set("verified", f.Verified)
+	}
+	return q
+}
+
+// Register a wire transfer received by the merchant (POST /private/transfers).
+// Older backends immediately reconcile the transfer with the exchange and
+// return the details, newer ones do this asynchronously and nil is returned.
+func (m *Merchant) AddTransfer(transfer TransferInformation) (*MerchantTrackTransferResponse, error) {
+	var trackResponse MerchantTrackTransferResponse
+	status, err := m.doRequest(http.MethodPost, "/private/transfers", nil, transfer, &trackResponse)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNoContent {
+		return nil, nil
+	}
+	return &trackResponse, nil
+}
+
+// List the wire transfers (GET /private/transfers)
+func (m *Merchant) GetTransfers(filter TransferFilter) (*TransferList, error) {
+	var transfers TransferList
+	_, err := m.doRequest(http.MethodGet, "/private/transfers", filter.query(), nil, &transfers)
+	if err != nil {
+		return nil, err
+	}
+	return &transfers, nil
+}
+
+// Delete a wire transfer (DELETE /private/transfers/$TID).
+// Fails with http.StatusConflict if the transfer was already reconciled.
+func (m *Merchant) DeleteTransfer(transferSerialId uint64) error {
+	_, err := m.doRequest(http.MethodDelete, "/private/transfers/"+strconv.FormatUint(transferSerialId, 10), nil, nil, nil)
+	return err
+}
+
+// Sums the deposit values and fees of the reconciled orders.
+// The sum of the deposit values minus the deposit fees and the wire fee
+// is the total amount transferred.
+func (r *MerchantTrackTransferResponse) DepositTotals() (*util.Amount, *util.Amount, error) {
+	value := util.NewAmount(r.Total.Currency, 0, 0)
+	fees := util.NewAmount(r.Total.Currency, 0, 0)
+	for _, d := range r.DepositsSums {
+		v, err := value.Add(d.DepositValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := fees.Add(d.DepositFee)
+		if err != nil {
+			return nil, nil, err
+		}
+		value = *v
+		fees = *f
+	}
+	return &value, &fees, nil
+}
@@ -0,0 +1,272 @@
+package merchant
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/schanzen/taler-go/pkg/util"
+)
+
+// Events that trigger webhooks
+const (
+	WebhookEventPay    = "pay"
+	WebhookEventRefund = "refund"
+)
+
+// Header used by WebhookReceiver for the shared secret
+// if none is configured
+const DefaultWebhookSecretHeader = "Taler-Webhook-Secret"
+
+// Maximum size of webhook bodies accepted by WebhookReceiver
+const maxWebhookBodySize = 1 << 20
+
+// Suggested body template for WebhookEventPay, as understood by
+// WebhookReceiver
+const PayWebhookBodyTemplate = `{"event_type":"pay","order_id":"{{order_id}}","contract_terms":{{{contract_terms}}}}`
+
+// Suggested body template for WebhookEventRefund, as understood by
+// WebhookReceiver
+const RefundWebhookBodyTemplate = `{"event_type":"refund","order_id":"{{order_id}}","refund_amount":"{{refund_amount}}","timestamp":{{{timestamp}}},"contract_terms":{{{contract_terms}}}}`
+
+type WebhookAddDetails struct {
+	// Webhook ID to use.
+	WebhookId string `json:"webhook_id"`
+
+	// The event of the webhook: why the webhook is used.
+	EventType string `json:"event_type"`
+
+	// URL of the webhook where the customer will be redirected.
+	Url string `json:"url"`
+
+	// Method used by the webhook.
+	HttpMethod string `json:"http_method"`
+
+	// Header template of the webhook, one "Name: value" per line.
+	HeaderTemplate string `json:"header_template,omitempty"`
+
+	// Body template by the webhook.
+	BodyTemplate string `json:"body_template,omitempty"`
+}
+
+type WebhookPatchDetails struct {
+	// The event of the webhook: why the webhook is used.
+	EventType string `json:"event_type"`
+
+	// URL of the webhook where the customer will be redirected.
+	Url string `json:"url"`
+
+	// Method used by the webhook.
+	HttpMethod string `json:"http_method"`
+
+	// Header template of the webhook, one "Name: value" per line.
+	HeaderTemplate string `json:"header_template,omitempty"`
+
+	// Body template by the webhook.
+	BodyTemplate string `json:"body_template,omitempty"`
+}
+
+type WebhookEntry struct {
+	// Webhook identifier, as found in the webhook.
+	WebhookId string `json:"webhook_id"`
+
+	// The event of the webhook: why the webhook is used.
+	EventType string `json:"event_type"`
+}
+
+type WebhookSummaryResponse struct {
+	// Return webhooks that are present in our backend.
+	Webhooks []WebhookEntry `json:"webhooks"`
+}
+
+type WebhookDetails struct {
+	// The event of the webhook: why the webhook is used.
+	EventType string `json:"event_type"`
+
+	// URL of the webhook where the customer will be redirected.
+	Url string `json:"url"`
+
+	// Method used by the webhook.
+	HttpMethod string `json:"http_method"`
+
+	// Header template of the webhook, one "Name: value" per line.
+	HeaderTemplate string `json:"header_template,omitempty"`
+
+	// Body template by the webhook.
+	BodyTemplate string `json:"body_template,omitempty"`
+}
+
+func webhookPath(webhookId string) string {
+	return "/private/webhooks/" + url.PathEscape(webhookId)
+}
+
+// Add a webhook (POST /private/webhooks)
+func (m *Merchant) AddWebhook(webhook WebhookAddDetails) error {
+	_, err := m.doRequest(http.MethodPost, "/private/webhooks", nil, webhook, nil)
+	return err
+}
+
+// List the webhooks (GET /private/webhooks)
+func (m *Merchant) GetWebhooks() (*WebhookSummaryResponse, error) {
+	var webhooks WebhookSummaryResponse
+	_, err := m.doRequest(http.MethodGet, "/private/webhooks", nil, nil, &webhooks)
+	if err != nil {
+		return nil, err
+	}
+	return &webhooks, nil
+}
+
+// Get the details of a webhook (GET /private/webhooks/$ID)
+func (m *Merchant) GetWebhook(webhookId string) (*WebhookDetails, error) {
+	var webhook WebhookDetails
+	_, err := m.doRequest(http.MethodGet, webhookPath(webhookId), nil, nil, &webhook)
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// Update a webhook (PATCH /private/webhooks/$ID)
+func (m *Merchant) UpdateWebhook(webhookId string, webhook WebhookPatchDetails) error {
+	_, err := m.doRequest(http.MethodPatch, webhookPath(webhookId), nil, webhook, nil)
+	return err
+}
+
+// Delete a webhook (DELETE /private/webhooks/$ID)
+func (m *Merchant) DeleteWebhook(webhookId string) error {
+	_, err := m.doRequest(http.MethodDelete, webhookPath(webhookId), nil, nil, nil)
+	return err
+}
+
+// A payment of an order
+type PayEvent struct {
+	// The order that was paid
+	OrderId string `json:"order_id"`
+
+	// The contract terms of the order
+	ContractTerms json.RawMessage `json:"contract_terms,omitempty"`
+}
+
+// A refund of an order
+type RefundEvent struct {
+	// The order that was refunded
+	OrderId string `json:"order_id"`
+
+	// The refunded amount
+	RefundAmount util.Amount `json:"refund_amount"`
+
+	// When the refund was granted
+	Timestamp *util.Timestamp `json:"timestamp,omitempty"`
+
+	// The contract terms of the order
+	ContractTerms json.RawMessage `json:"contract_terms,omitempty"`
+}
+
+// Receives webhook calls of the merchant backend that use
+// PayWebhookBodyTemplate or RefundWebhookBodyTemplate and dispatches
+// them to the callbacks. Callbacks that are nil are skipped.
+type WebhookReceiver struct {
+	// The shared secret the backend sends in SecretHeader
+	Secret string
+
+	// The header carrying the secret, DefaultWebhookSecretHeader if empty
+	SecretHeader string
+
+	// Called for WebhookEventPay
+	OnPay func(ctx context.Context, event PayEvent) error
+
+	// Called for WebhookEventRefund
+	OnRefund func(ctx context.Context, event RefundEvent) error
+
+	// Logger for callback errors, slog.Default() if nil
+	Logger *slog.Logger
+}
+
+func (wr *WebhookReceiver) logger() *slog.Logger {
+	if wr.Logger != nil {
+		return wr.Logger
+	}
+	return slog.Default()
+}
+
+func (wr *WebhookReceiver) secretHeader() string {
+	if wr.SecretHeader == "" {
+		return DefaultWebhookSecretHeader
+	}
+	return wr.SecretHeader
+}
+
+// Returns the details for a webhook calling this receiver at hookUrl
+func (wr *WebhookReceiver) Webhook(webhookId string, eventType string, hookUrl string) WebhookAddDetails {
+	webhook := WebhookAddDetails{
+		WebhookId:      webhookId,
+		EventType:      eventType,
+		Url:            hookUrl,
+		HttpMethod:     http.MethodPost,
+		HeaderTemplate: "Content-Type: application/json\n" + wr.secretHeader() + ": " + wr.Secret,
+	}
+	switch eventType {
+	case WebhookEventPay:
+		webhook.BodyTemplate = PayWebhookBodyTemplate
+	case WebhookEventRefund:
+		webhook.BodyTemplate = RefundWebhookBodyTemplate
+	}
+	return webhook
+}
+
+func (wr *WebhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	secret := r.Header.Get(wr.secretHeader())
+	if wr.Secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(wr.Secret)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var body json.RawMessage
+	var event struct {
+		EventType string `json:"event_type"`
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodySize)).Decode(&body)
+	if err == nil {
+		err = json.Unmarshal(body, &event)
+	}
+	if err != nil {
+		http.Error(w, "malformed webhook body", http.StatusBadRequest)
+		return
+	}
+	switch event.EventType {
+	case WebhookEventPay:
+		var pay PayEvent
+		if err := json.Unmarshal(body, &pay); err != nil {
+			http.Error(w, "malformed pay event", http.StatusBadRequest)
+			return
+		}
+		if wr.OnPay != nil {
+			err = wr.OnPay(r.Context(), pay)
+		}
+	case WebhookEventRefund:
+		var refund RefundEvent
+		if err := json.Unmarshal(body, &refund); err != nil {
+			http.Error(w, "malformed refund event", http.StatusBadRequest)
+			return
+		}
+		if wr.OnRefund != nil {
+			err = wr.OnRefund(r.Context(), refund)
+		}
+	default:
+		http.Error(w, "unknown event type", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		wr.logger().Error("webhook callback failed", "event_type", event.EventType, "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package tos
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// A media range of an Accept header
+type mediaRange struct {
+	// The type, may be "*"
+	typ string
+
+	// The subtype, may be "*"
+	subtype string
+
+	// The quality value
+	q float64
+}
+
+// Parses the media ranges of all Accept header values.
+// Invalid ranges are skipped.
+func parseAccept(headers []string) []mediaRange {
+	var ranges []mediaRange
+	for _, header := range headers {
+		for _, part := range strings.Split(header, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			typ, subtype, found := strings.Cut(mediaType, "/")
+			if !found || (typ == "*" && subtype != "*") {
+				continue
+			}
+			q := 1.0
+			if qs, ok := params["q"]; ok {
+				q, err = strconv.ParseFloat(qs, 64)
+				if err != nil || q < 0 || q > 1 {
+					continue
+				}
+			}
+			ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+		}
+	}
+	return ranges
+}
+
+// Returns the quality of contentType according to the most specific
+// matching media range, or -1 if no range matches.
+func quality(ranges []mediaRange, contentType string) float64 {
+	typ, subtype, _ := strings.Cut(strings.ToLower(contentType), "/")
+	q := -1.0
+	specificity := -1
+	for _, mr := range ranges {
+		s := -1
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*":
+			s = 0
+		}
+		if s > specificity {
+			specificity = s
+			q = mr.q
+		}
+	}
+	return q
+}
+
+// Picks the type from supported (and the default type) that is most
+// acceptable according to the Accept headers. Without Accept header
+// the default type is returned. Ties are broken in favour of the
+// default type and then the order of supported.
+// Returns false if none of the types is acceptable.
+func negotiateContentType(headers []string, supported []string, defaultType string) (string, bool) {
+	ranges := parseAccept(headers)
+	if len(ranges) == 0 {
+		return defaultType, true
+	}
+	candidates := append([]string{defaultType}, supported...)
+	best := ""
+	bestQ := 0.0
+	for _, c := range candidates {
+		q := quality(ranges, c)
+		if q > bestQ {
+			best = c
+			bestQ = q
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,255 @@
+package tos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Returned by AcceptanceStore.LatestAcceptance if the account
+// never accepted any terms
+var ErrNotAccepted = errors.New("terms of service not accepted")
+
+// The acceptance of the terms of service by an account
+type Acceptance struct {
+	// The user or account ID
+	AccountID string
+
+	// The accepted terms version, see TalerTosConfig.TermsVersion
+	TermsVersion string
+
+	// The language of the accepted document
+	Language string
+
+	// When the terms were accepted
+	AcceptedAt time.Time
+}
+
+// Storage for terms of service acceptances
+type AcceptanceStore interface {
+	// Record an acceptance
+	RecordAcceptance(ctx context.Context, a Acceptance) error
+
+	// Returns the most recent acceptance of the account,
+	// or ErrNotAccepted
+	LatestAcceptance(ctx context.Context, accountID string) (*Acceptance, error)
+}
+
+// AcceptanceStore keeping all acceptances in memory
+type MemoryAcceptanceStore struct {
+	mu          sync.RWMutex
+	acceptances map[string][]Acceptance
+}
+
+func NewMemoryAcceptanceStore() *MemoryAcceptanceStore {
+	return &MemoryAcceptanceStore{
+		acceptances: make(map[string][]Acceptance),
+	}
+}
+
+func (s *MemoryAcceptanceStore) RecordAcceptance(ctx context.Context, a Acceptance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acceptances[a.AccountID] = append(s.acceptances[a.AccountID], a)
+	return nil
+}
+
+func (s *MemoryAcceptanceStore) LatestAcceptance(ctx context.Context, accountID string) (*Acceptance, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	history := s.acceptances[accountID]
+	if len(history) == 0 {
+		return nil, ErrNotAccepted
+	}
+	a := history[len(history)-1]
+	return &a, nil
+}
+
+// Valid table names for SQLAcceptanceStore
+var rexTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// AcceptanceStore in a Postgres table
+type SQLAcceptanceStore struct {
+	// The database
+	DB *sql.DB
+
+	// The table, optionally schema qualified
+	Table string
+}
+
+// Create a store using the given table, see CreateTable
+func NewSQLAcceptanceStore(db *sql.DB, table string) (*SQLAcceptanceStore, error) {
+	if !rexTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %s", table)
+	}
+	return &SQLAcceptanceStore{
+		DB:    db,
+		Table: table,
+	}, nil
+}
+
+// Creates the table if it does not exist yet.
+// Services using DBInit should rather create it in one of their patches.
+func (s *SQLAcceptanceStore) CreateTable(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+  (acceptance_serial INT8 GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY
+  ,account_id TEXT NOT NULL
+  ,terms_version TEXT NOT NULL
+  ,language TEXT NOT NULL
+  ,accepted_at INT8 NOT NULL
+  );`, s.Table))
+	return err
+}
+
+func (s *SQLAcceptanceStore) RecordAcceptance(ctx context.Context, a Acceptance) error {
+	_, err := s.DB.ExecContext(ctx,
+		fmt.Sprintf(`INSERT INTO %s (account_id, terms_version, language, accepted_at) VALUES ($1, $2, $3, $4);`, s.Table),
+		a.AccountID, a.TermsVersion, a.Language, a.AcceptedAt.UnixMicro())
+	return err
+}
+
+func (s *SQLAcceptanceStore) LatestAcceptance(ctx context.Context, accountID string) (*Acceptance, error) {
+	var a Acceptance
+	var acceptedAt int64
+	err := s.DB.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT account_id, terms_version, language, accepted_at FROM %s WHERE account_id=$1 ORDER BY accepted_at DESC, acceptance_serial DESC LIMIT 1;`, s.Table),
+		accountID).Scan(&a.AccountID, &a.TermsVersion, &a.Language, &acceptedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotAccepted
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.AcceptedAt = time.UnixMicro(acceptedAt)
+	return &a, nil
+}
+
+// Taler error codes used by AcceptanceChecker
+const (
+	// TALER_EC_GENERIC_UNAUTHORIZED
+	ErrorCodeGenericUnauthorized = 40
+
+	// TALER_EC_GENERIC_FORBIDDEN
+	ErrorCodeGenericForbidden = 44
+
+	// TALER_EC_GENERIC_DB_FETCH_FAILED
+	ErrorCodeGenericDBFetchFailed = 53
+)
+
+// A Taler error response
+type TalerError struct {
+	// The Taler error code
+	Code int `json:"code"`
+
+	// Human-readable description of the error
+	Hint string `json:"hint,omitempty"`
+
+	// Optional details about the error
+	Detail string `json:"detail,omitempty"`
+}
+
+// Rejects requests of accounts that have not accepted the current terms
+type AcceptanceChecker struct {
+	// Where acceptances are recorded
+	Store AcceptanceStore
+
+	// The current terms version
+	TermsVersion string
+
+	// Returns the account ID of a request, false if the request is
+	// not authenticated
+	AccountID func(r *http.Request) (string, bool)
+
+	// The Taler error code of rejections, ErrorCodeGenericForbidden
+	// if zero
+	ErrorCode int
+
+	// The HTTP status of rejections, http.StatusForbidden if zero
+	Status int
+
+	// Logger to use, slog.Default() if nil
+	Logger *slog.Logger
+}
+
+func (c *AcceptanceChecker) logger() *slog.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return slog.Default()
+}
+
+// Record that the account accepted the current terms
+func (c *AcceptanceChecker) Accept(ctx context.Context, accountID string, language string) error {
+	return c.Store.RecordAcceptance(ctx, Acceptance{
+		AccountID:    accountID,
+		TermsVersion: c.TermsVersion,
+		Language:     language,
+		AcceptedAt:   time.Now(),
+	})
+}
+
+// Check if the account accepted the current terms
+func (c *AcceptanceChecker) HasAccepted(ctx context.Context, accountID string) (bool, error) {
+	a, err := c.Store.LatestAcceptance(ctx, accountID)
+	if errors.Is(err, ErrNotAccepted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return a.TermsVersion == c.TermsVersion, nil
+}
+
+func (c *AcceptanceChecker) reject(w http.ResponseWriter, status int, talerErr TalerError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(talerErr)
+}
+
+// Wraps next so that it is only called for accounts that accepted the
+// current terms.
+func (c *AcceptanceChecker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accountID, ok := c.AccountID(r)
+		if !ok {
+			c.reject(w, http.StatusUnauthorized, TalerError{
+				Code: ErrorCodeGenericUnauthorized,
+				Hint: "account unknown",
+			})
+			return
+		}
+		accepted, err := c.HasAccepted(r.Context(), accountID)
+		if err != nil {
+			c.logger().Error("failed to check terms of service acceptance", "account", accountID, "error", err)
+			c.reject(w, http.StatusInternalServerError, TalerError{
+				Code: ErrorCodeGenericDBFetchFailed,
+				Hint: "unable to check terms of service acceptance",
+			})
+			return
+		}
+		if !accepted {
+			status := c.Status
+			if status == 0 {
+				status = http.StatusForbidden
+			}
+			code := c.ErrorCode
+			if code == 0 {
+				code = ErrorCodeGenericForbidden
+			}
+			c.reject(w, status, TalerError{
+				Code:   code,
+				Hint:   "terms of service not accepted",
+				Detail: "current terms version is " + c.TermsVersion,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,145 @@
+package tos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io/fs"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Content codings supported in order of preference
+var supportedEncodings = []string{"gzip", "deflate"}
+
+// Picks the preferred content coding from the Accept-Encoding header.
+// Returns the empty string for identity.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		coding, params, err := mime.ParseMediaType("x/" + part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(qs, 64)
+			if err != nil {
+				continue
+			}
+		}
+		qualities[strings.TrimPrefix(coding, "x/")] = q
+	}
+	best := ""
+	bestQ := 0.0
+	for _, enc := range supportedEncodings {
+		q, ok := qualities[enc]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best = enc
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// Compresses content with the given content coding
+func compress(content []byte, encoding string) ([]byte, error) {
+	var b bytes.Buffer
+	var err error
+	switch encoding {
+	case "gzip":
+		zw := gzip.NewWriter(&b)
+		_, err = zw.Write(content)
+		if err == nil {
+			err = zw.Close()
+		}
+	case "deflate":
+		zw := zlib.NewWriter(&b)
+		_, err = zw.Write(content)
+		if err == nil {
+			err = zw.Close()
+		}
+	default:
+		return content, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// A compressed document
+type encodedDocument struct {
+	// Modification time of the uncompressed document
+	modTime time.Time
+
+	// Size of the uncompressed document
+	size int
+
+	// The compressed content
+	content []byte
+}
+
+// Compressed documents by language, type and coding
+type encodingCache struct {
+	mu      sync.Mutex
+	entries map[string]*encodedDocument
+}
+
+func newEncodingCache() *encodingCache {
+	return &encodingCache{
+		entries: make(map[string]*encodedDocument),
+	}
+}
+
+// Replaces the content of doc with its compressed form. A precompressed
+// .gz sibling of the document file is used for gzip if present and not
+// older than the document.
+func (h *Handler) encode(doc *legalDocument, encoding string) error {
+	if encoding == "gzip" && doc.path != "" {
+		gzFile := doc.path + ".gz"
+		info, err := fs.Stat(h.fsys, gzFile)
+		if err == nil && info.ModTime().Before(doc.modTime) {
+			h.cfg.logger().Debug("ignoring stale precompressed legal document", "file", gzFile)
+		} else if err == nil {
+			if content, err := fs.ReadFile(h.fsys, gzFile); err == nil {
+				h.cfg.logger().Debug("using precompressed legal document", "file", gzFile)
+				doc.content = content
+				doc.encoding = encoding
+				return nil
+			}
+		}
+	}
+	key := doc.language + "\x00" + doc.contentType + "\x00" + encoding
+	c := h.encodings
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok || !cached.modTime.Equal(doc.modTime) || cached.size != len(doc.content) {
+		content, err := compress(doc.content, encoding)
+		if err != nil {
+			return err
+		}
+		cached = &encodedDocument{
+			modTime: doc.modTime,
+			size:    len(doc.content),
+			content: content,
+		}
+		c.mu.Lock()
+		c.entries[key] = cached
+		c.mu.Unlock()
+	}
+	doc.content = cached.content
+	doc.encoding = encoding
+	return nil
+}
@@ -0,0 +1,287 @@
+package tos
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The block types of the supported Markdown subset
+const (
+	blockParagraph = iota
+	blockHeading
+	blockCode
+	blockQuote
+	blockList
+	blockRule
+)
+
+// A block of a Markdown document
+type mdBlock struct {
+	// The block type
+	kind int
+
+	// Heading level
+	level int
+
+	// Ordered list
+	ordered bool
+
+	// Lines of paragraphs, quotes and code, items of lists
+	lines []string
+}
+
+var (
+	rexHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	rexRule        = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	rexBullet      = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	rexOrdered     = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	rexCodeSpan    = regexp.MustCompile("`([^`]+)`")
+	rexLink        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	rexStrong      = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	rexEmphasis    = regexp.MustCompile(`(^|[^\w*])[*_]([^*_\s][^*_]*?)[*_]($|[^\w*])`)
+	rexPlaceholder = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// Splits a Markdown document into blocks. Supported are ATX headings,
+// paragraphs, fenced code, block quotes, flat lists and rules.
+func parseMarkdown(src []byte) []mdBlock {
+	var blocks []mdBlock
+	var cur *mdBlock
+	flush := func() {
+		if cur != nil {
+			blocks = append(blocks, *cur)
+			cur = nil
+		}
+	}
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			flush()
+			fence := trimmed[:3]
+			code := mdBlock{kind: blockCode}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code.lines = append(code.lines, lines[i])
+			}
+			blocks = append(blocks, code)
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if m := rexHeading.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			blocks = append(blocks, mdBlock{kind: blockHeading, level: len(m[1]), lines: []string{m[2]}})
+			continue
+		}
+		if rexRule.MatchString(line) {
+			flush()
+			blocks = append(blocks, mdBlock{kind: blockRule})
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			text := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			if cur == nil || cur.kind != blockQuote {
+				flush()
+				cur = &mdBlock{kind: blockQuote}
+			}
+			cur.lines = append(cur.lines, text)
+			continue
+		}
+		bullet := rexBullet.FindStringSubmatch(line)
+		ordered := rexOrdered.FindStringSubmatch(line)
+		if bullet != nil || ordered != nil {
+			item := ""
+			if bullet != nil {
+				item = bullet[1]
+			} else {
+				item = ordered[1]
+			}
+			if cur == nil || cur.kind != blockList || cur.ordered != (ordered != nil) {
+				flush()
+				cur = &mdBlock{kind: blockList, ordered: ordered != nil}
+			}
+			cur.lines = append(cur.lines, item)
+			continue
+		}
+		if cur != nil && cur.kind == blockList && line != trimmed {
+			// Indented continuation of the last item
+			cur.lines[len(cur.lines)-1] += " " + trimmed
+			continue
+		}
+		if cur == nil || cur.kind != blockParagraph {
+			flush()
+			cur = &mdBlock{kind: blockParagraph}
+		}
+		cur.lines = append(cur.lines, trimmed)
+	}
+	flush()
+	return blocks
+}
+
+// Renders inline markup (code spans, links, strong and emphasis) as HTML
+func inlineHTML(s string) string {
+	var codes []string
+	s = rexCodeSpan.ReplaceAllStringFunc(s, func(m string) string {
+		codes = append(codes, "<code>"+html.EscapeString(m[1:len(m)-1])+"</code>")
+		return fmt.Sprintf("\x00%d\x00", len(codes)-1)
+	})
+	s = html.EscapeString(s)
+	s = rexLink.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	s = rexStrong.ReplaceAllString(s, "<strong>$2</strong>")
+	s = rexEmphasis.ReplaceAllString(s, "$1<em>$2</em>$3")
+	return rexPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+		var idx int
+		fmt.Sscanf(m[1:len(m)-1], "%d", &idx)
+		return codes[idx]
+	})
+}
+
+// Removes inline markup, links are rendered as "text (url)"
+func inlineText(s string) string {
+	s = rexCodeSpan.ReplaceAllString(s, "$1")
+	s = rexLink.ReplaceAllString(s, "$1 ($2)")
+	s = rexStrong.ReplaceAllString(s, "$2")
+	return rexEmphasis.ReplaceAllString(s, "$1$2$3")
+}
+
+// Converts Markdown to an HTML document
+func markdownToHTML(src []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"></head>\n<body>\n")
+	for _, block := range parseMarkdown(src) {
+		switch block.kind {
+		case blockHeading:
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", block.level, inlineHTML(block.lines[0]), block.level)
+		case blockParagraph:
+			fmt.Fprintf(&b, "<p>%s</p>\n", inlineHTML(strings.Join(block.lines, "\n")))
+		case blockQuote:
+			fmt.Fprintf(&b, "<blockquote><p>%s</p></blockquote>\n", inlineHTML(strings.Join(block.lines, "\n")))
+		case blockCode:
+			fmt.Fprintf(&b, "<pre><code>%s</code></pre>\n", html.EscapeString(strings.Join(block.lines, "\n")))
+		case blockRule:
+			b.WriteString("<hr>\n")
+		case blockList:
+			tag := "ul"
+			if block.ordered {
+				tag = "ol"
+			}
+			fmt.Fprintf(&b, "<%s>\n", tag)
+			for _, item := range block.lines {
+				fmt.Fprintf(&b, "<li>%s</li>\n", inlineHTML(item))
+			}
+			fmt.Fprintf(&b, "</%s>\n", tag)
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.Bytes()
+}
+
+// Converts Markdown to plain text
+func markdownToText(src []byte) []byte {
+	var b bytes.Buffer
+	for i, block := range parseMarkdown(src) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch block.kind {
+		case blockHeading:
+			text := inlineText(block.lines[0])
+			b.WriteString(text + "\n")
+			if block.level <= 2 {
+				underline := "="
+				if block.level == 2 {
+					underline = "-"
+				}
+				b.WriteString(strings.Repeat(underline, len([]rune(text))) + "\n")
+			}
+		case blockParagraph:
+			for _, line := range block.lines {
+				b.WriteString(inlineText(line) + "\n")
+			}
+		case blockQuote:
+			for _, line := range block.lines {
+				b.WriteString("  " + inlineText(line) + "\n")
+			}
+		case blockCode:
+			for _, line := range block.lines {
+				b.WriteString("    " + line + "\n")
+			}
+		case blockRule:
+			b.WriteString(strings.Repeat("-", 72) + "\n")
+		case blockList:
+			for n, item := range block.lines {
+				if block.ordered {
+					fmt.Fprintf(&b, "%d. %s\n", n+1, inlineText(item))
+				} else {
+					b.WriteString("- " + inlineText(item) + "\n")
+				}
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+// Converters from Markdown by target MIME type
+var markdownConverters = map[string]func([]byte) []byte{
+	"text/html":  markdownToHTML,
+	"text/plain": markdownToText,
+}
+
+// A converted document
+type renderedDocument struct {
+	// Modification time of the source
+	modTime time.Time
+
+	// Size of the source
+	size int64
+
+	// The converted content
+	content []byte
+}
+
+// Converted documents by source path and target type.
+// Entries are replaced when the source changes.
+type renderCache struct {
+	mu      sync.Mutex
+	entries map[string]*renderedDocument
+}
+
+func newRenderCache() *renderCache {
+	return &renderCache{
+		entries: make(map[string]*renderedDocument),
+	}
+}
+
+// Returns srcFile converted to fileType, converting it only if it
+// changed since the last call
+func (c *renderCache) render(fsys fs.FS, srcFile string, info fs.FileInfo, fileType string, convert func([]byte) []byte) ([]byte, error) {
+	key := srcFile + "\x00" + fileType
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.content, nil
+	}
+	src, err := fs.ReadFile(fsys, srcFile)
+	if err != nil {
+		return nil, err
+	}
+	rendered := &renderedDocument{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		content: convert(src),
+	}
+	c.mu.Lock()
+	c.entries[key] = rendered
+	c.mu.Unlock()
+	return rendered.content, nil
+}
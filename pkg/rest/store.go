@@ -0,0 +1,310 @@
+package tos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DocumentStore keeps an in-memory copy of a directory of legal
+// documents and implements fs.FS on top of it, so that documents can be
+// served without disk I/O per request. The copy is refreshed with
+// Reload, Watch or WatchSignals.
+type DocumentStore struct {
+	// The indexed file system
+	source fs.FS
+
+	// Logger to use, slog.Default() if nil
+	Logger *slog.Logger
+
+	mu       sync.RWMutex
+	snapshot *storeSnapshot
+}
+
+type storeSnapshot struct {
+	// Regular files by path
+	files map[string]*memFile
+
+	// Directory entries by directory path
+	dirs map[string][]fs.DirEntry
+
+	// Fingerprint of the indexed files, see fingerprint
+	fingerprint string
+
+	// Version of the documents, see TalerTosConfig.TermsVersion
+	version string
+}
+
+type memFile struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (f *memFile) Name() string               { return f.name }
+func (f *memFile) Size() int64                { return int64(len(f.data)) }
+func (f *memFile) Mode() fs.FileMode          { return f.mode }
+func (f *memFile) ModTime() time.Time         { return f.modTime }
+func (f *memFile) IsDir() bool                { return f.mode.IsDir() }
+func (f *memFile) Sys() any                   { return nil }
+func (f *memFile) Type() fs.FileMode          { return f.mode.Type() }
+func (f *memFile) Info() (fs.FileInfo, error) { return f, nil }
+
+// An open regular file
+type openMemFile struct {
+	*bytes.Reader
+	info *memFile
+}
+
+func (f *openMemFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openMemFile) Close() error               { return nil }
+
+// An open directory
+type openMemDir struct {
+	info    *memFile
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *openMemDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *openMemDir) Close() error               { return nil }
+func (d *openMemDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *openMemDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(rest), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return slices.Clone(rest[:n]), nil
+}
+
+// Create a store and index the documents in source
+func NewDocumentStore(source fs.FS) (*DocumentStore, error) {
+	s := &DocumentStore{
+		source: source,
+	}
+	err := s.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create a store and index the documents in the directory dir
+func NewDocumentStoreFromDir(dir string) (*DocumentStore, error) {
+	return NewDocumentStore(os.DirFS(dir))
+}
+
+func (s *DocumentStore) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+// Computes a fingerprint over the names, sizes and modification times
+// of all files in source without reading them
+func fingerprint(source fs.FS) (string, error) {
+	var b strings.Builder
+	err := fs.WalkDir(source, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %d %d\n", p, info.Mode(), info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
+
+// Re-reads all documents from the source.
+// On error the previous copy is kept.
+func (s *DocumentStore) Reload() error {
+	fp, err := fingerprint(s.source)
+	if err != nil {
+		return err
+	}
+	snap := &storeSnapshot{
+		files:       make(map[string]*memFile),
+		dirs:        make(map[string][]fs.DirEntry),
+		fingerprint: fp,
+	}
+	var newest time.Time
+	err = fs.WalkDir(s.source, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f := &memFile{
+			name:    path.Base(p),
+			mode:    info.Mode(),
+			modTime: info.ModTime(),
+		}
+		if p != "." {
+			dir := path.Dir(p)
+			snap.dirs[dir] = append(snap.dirs[dir], f)
+		}
+		if d.IsDir() {
+			if _, ok := snap.dirs[p]; !ok {
+				snap.dirs[p] = nil
+			}
+			snap.files[p] = f
+			return nil
+		}
+		f.data, err = fs.ReadFile(s.source, p)
+		if err != nil {
+			return err
+		}
+		snap.files[p] = f
+		if f.modTime.After(newest) {
+			newest = f.modTime
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	snap.version = formatVersion(newest)
+	s.mu.Lock()
+	s.snapshot = snap
+	s.mu.Unlock()
+	s.logger().Info("legal documents loaded", "files", len(snap.files)-len(snap.dirs))
+	return nil
+}
+
+// Reloads the documents if any file was added, removed or modified
+// since the last load. Returns true if the documents were reloaded.
+func (s *DocumentStore) ReloadIfChanged() (bool, error) {
+	fp, err := fingerprint(s.source)
+	if err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	unchanged := s.snapshot.fingerprint == fp
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	return true, s.Reload()
+}
+
+// Checks for changed files every interval and reloads the documents
+// until ctx is cancelled.
+func (s *DocumentStore) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := s.ReloadIfChanged()
+			if err != nil {
+				s.logger().Error("failed to reload legal documents", "error", err)
+			}
+		}
+	}
+}
+
+// Reloads the documents whenever one of the signals (SIGHUP if none are
+// given) is received until ctx is cancelled.
+func (s *DocumentStore) WatchSignals(ctx context.Context, sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	defer signal.Stop(c)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-c:
+			s.logger().Info("reloading legal documents", "signal", sig.String())
+			err := s.Reload()
+			if err != nil {
+				s.logger().Error("failed to reload legal documents", "error", err)
+			}
+		}
+	}
+}
+
+func (s *DocumentStore) current() *storeSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+func (s *DocumentStore) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	snap := s.current()
+	f, ok := snap.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if f.IsDir() {
+		return &openMemDir{info: f, entries: snap.dirs[name]}, nil
+	}
+	return &openMemFile{Reader: bytes.NewReader(f.data), info: f}, nil
+}
+
+func (s *DocumentStore) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := s.current().files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
+
+func (s *DocumentStore) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	f, ok := s.current().files[name]
+	if !ok || f.IsDir() {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(f.data), nil
+}
+
+func (s *DocumentStore) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, ok := s.current().dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(entries), nil
+}
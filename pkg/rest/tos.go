@@ -1,108 +1,414 @@
 package tos
 
 import (
-	"fmt"
-	"log"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"log/slog"
 	"mime"
 	"net/http"
 	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/text/language"
 )
 
 type TalerTosConfig struct {
-  // The default file type
-  DefaultFileType string
+	// The default file type
+	DefaultFileType string
 
-  // Supported file types
-  SupportedFileTypes []string
+	// Supported file types
+	SupportedFileTypes []string
 
-  // Default language
-  DefaultLanguage string
+	// Default language
+	DefaultLanguage string
+
+	// Version of the documents, sent as Taler-Terms-Version.
+	// If empty, the newest modification time of all documents (in all
+	// languages and formats) is used, so that translations share
+	// one version.
+	TermsVersion string
+
+	// Logger to use, slog.Default() if nil
+	Logger *slog.Logger
 }
 
-func ServiceTermsResponse(w http.ResponseWriter, r *http.Request, termsdatahome string, cfg TalerTosConfig) {
-	fileType := cfg.DefaultFileType
-	termsLocation := termsdatahome
-	for _, typ := range r.Header["Accept"] {
-		for _, a := range cfg.SupportedFileTypes {
-			if typ == a {
-				fileType = a
-			}
+func (cfg *TalerTosConfig) logger() *slog.Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
+	}
+	return slog.Default()
+}
+
+// A legal document found for a request
+type legalDocument struct {
+	// The content
+	content []byte
+
+	// The MIME type
+	contentType string
+
+	// The language tag
+	language string
+
+	// The modification time
+	modTime time.Time
+
+	// The file in the file system, empty if converted
+	path string
+
+	// The content coding, empty for identity
+	encoding string
+}
+
+// Formats the newest modification time of the documents as version
+func formatVersion(newest time.Time) string {
+	return newest.UTC().Format("20060102150405")
+}
+
+// Returns the newest modification time of all files in fsys
+func newestModTime(fsys fs.FS) time.Time {
+	var newest time.Time
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
 		}
+		info, err := d.Info()
+		if err == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return newest
+}
+
+// The version derived from the modification times of the documents.
+// The file system is only walked again when a served document has a
+// different modification time than when it was last served.
+type versionCache struct {
+	mu       sync.Mutex
+	version  string
+	modTimes map[string]time.Time
+}
+
+func newVersionCache() *versionCache {
+	return &versionCache{
+		modTimes: make(map[string]time.Time),
+	}
+}
+
+func (c *versionCache) get(fsys fs.FS, doc *legalDocument) string {
+	key := doc.language + "\x00" + doc.contentType
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if modTime, ok := c.modTimes[key]; !ok || !modTime.Equal(doc.modTime) {
+		c.version = formatVersion(newestModTime(fsys))
+		c.modTimes[key] = doc.modTime
 	}
+	return c.version
+}
 
-	if len(r.Header.Get("Accept-Language")) != 0 {
-		acceptLangs, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
-		for _, lang := range acceptLangs {
-			extensions, _ := mime.ExtensionsByType(fileType)
-			for _, ext := range extensions {
-				docFile := fmt.Sprintf("%s/%s/0%s", termsLocation, lang.String(), ext)
-				log.Printf("Trying %s\n", docFile)
-				fileBytes, err := os.ReadFile(docFile)
-				if nil == err {
-					w.Header().Set("Content-Type", fileType)
-					w.Write(fileBytes)
-					return
-				}
+// The version of the documents, see TalerTosConfig.TermsVersion.
+// A DocumentStore computes it once per reload.
+func (h *Handler) termsVersion(doc *legalDocument) string {
+	if h.cfg.TermsVersion != "" {
+		return h.cfg.TermsVersion
+	}
+	if store, ok := h.fsys.(*DocumentStore); ok {
+		return store.current().version
+	}
+	return h.versions.get(h.fsys, doc)
+}
+
+// A strong ETag over the (encoded) document content
+func (d *legalDocument) etag() string {
+	h := sha256.Sum256(d.content)
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
+
+// Extensions for types the system MIME database may not know
+var fallbackExtensions = map[string][]string{
+	"text/plain":    {".txt"},
+	"text/markdown": {".md"},
+}
+
+// Returns the file extensions of fileType
+func extensionsByType(fileType string) []string {
+	extensions, _ := mime.ExtensionsByType(fileType)
+	if len(extensions) == 0 {
+		extensions = fallbackExtensions[fileType]
+	}
+	return extensions
+}
+
+// Tries to read <lang>/0<ext> from the file system for all extensions of
+// fileType. If none exists, the document is converted from <lang>/0.md
+// if possible.
+func (h *Handler) readDocument(lang string, fileType string) (*legalDocument, bool) {
+	for _, ext := range extensionsByType(fileType) {
+		docFile := path.Join(lang, "0"+ext)
+		h.cfg.logger().Debug("trying legal document", "file", docFile, "language", lang, "type", fileType)
+		info, err := fs.Stat(h.fsys, docFile)
+		if err != nil {
+			continue
+		}
+		content, err := fs.ReadFile(h.fsys, docFile)
+		if err != nil {
+			continue
+		}
+		return &legalDocument{
+			content:     content,
+			contentType: fileType,
+			language:    lang,
+			modTime:     info.ModTime(),
+			path:        docFile,
+		}, true
+	}
+	convert, ok := markdownConverters[fileType]
+	if !ok {
+		return nil, false
+	}
+	srcFile := path.Join(lang, "0.md")
+	info, err := fs.Stat(h.fsys, srcFile)
+	if err != nil {
+		return nil, false
+	}
+	h.cfg.logger().Debug("converting legal document", "file", srcFile, "language", lang, "type", fileType)
+	content, err := h.renders.render(h.fsys, srcFile, info, fileType, convert)
+	if err != nil {
+		h.cfg.logger().Warn("failed to convert legal document", "file", srcFile, "type", fileType, "error", err)
+		return nil, false
+	}
+	return &legalDocument{
+		content:     content,
+		contentType: fileType,
+		language:    lang,
+		modTime:     info.ModTime(),
+	}, true
+}
+
+// Returns the languages (directories) present in fsys with the default
+// language first and the others in lexical order.
+func availableLanguages(fsys fs.FS, defaultLanguage string) []string {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil
+	}
+	var langs []string
+	hasDefault := false
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := language.Parse(e.Name()); err != nil {
+			continue
+		}
+		if e.Name() == defaultLanguage {
+			hasDefault = true
+			continue
+		}
+		langs = append(langs, e.Name())
+	}
+	if hasDefault {
+		langs = append([]string{defaultLanguage}, langs...)
+	}
+	return langs
+}
+
+// Matches the Accept-Language header against the available languages,
+// so that e.g. "de" is served for "de-CH".
+// Returns false if none of the languages is a reasonable match.
+func matchLanguage(acceptLanguage string, available []string) (string, bool) {
+	if acceptLanguage == "" || len(available) == 0 {
+		return "", false
+	}
+	acceptLangs, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(acceptLangs) == 0 {
+		return "", false
+	}
+	tags := make([]language.Tag, len(available))
+	for i, lang := range available {
+		tags[i] = language.Make(lang)
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(acceptLangs...)
+	if confidence == language.No {
+		return "", false
+	}
+	return available[index], true
+}
+
+// Check the conditional request headers.
+// Returns true if the client's copy is still valid.
+func notModified(r *http.Request, doc *legalDocument) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := doc.etag()
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
 			}
 		}
+		return false
 	}
-	// Default document in expected/default format
-	defaultLanguage := cfg.DefaultLanguage
-	extensions, _ := mime.ExtensionsByType(fileType)
-	for _, ext := range extensions {
-		docFile := fmt.Sprintf("%s/%s/0%s", termsLocation, defaultLanguage, ext)
-		log.Println("Trying " + docFile)
-		fileBytes, err := os.ReadFile(docFile)
-		if nil == err {
-			w.Header().Set("Content-Type", fileType)
-			w.Write(fileBytes)
-			return
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !doc.modTime.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !doc.modTime.Truncate(time.Second).After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Serves the legal document best matching the request
+func (h *Handler) serveDocument(w http.ResponseWriter, r *http.Request) {
+	cfg := &h.cfg
+	w.Header().Set("Vary", "Accept, Accept-Language, Accept-Encoding")
+	fileType, acceptable := negotiateContentType(r.Header["Accept"], cfg.SupportedFileTypes, cfg.DefaultFileType)
+	if !acceptable {
+		w.WriteHeader(http.StatusNotAcceptable)
+		return
+	}
+
+	langs := availableLanguages(h.fsys, cfg.DefaultLanguage)
+	if len(langs) != 0 {
+		w.Header().Set("Avail-Languages", strings.Join(langs, ", "))
+	}
+	var doc *legalDocument
+	found := false
+	if lang, ok := matchLanguage(r.Header.Get("Accept-Language"), langs); ok {
+		doc, found = h.readDocument(lang, fileType)
+	}
+	if !found {
+		// Default document in expected/default format
+		doc, found = h.readDocument(cfg.DefaultLanguage, fileType)
+	}
+	if !found {
+		cfg.logger().Warn("no legal document found", "type", fileType)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if encoding := negotiateEncoding(r.Header.Get("Accept-Encoding")); encoding != "" {
+		err := h.encode(doc, encoding)
+		if err != nil {
+			cfg.logger().Warn("failed to compress legal document", "encoding", encoding, "error", err)
+		}
+	}
+	if doc.encoding != "" {
+		w.Header().Set("Content-Encoding", doc.encoding)
+	}
+	w.Header().Set("ETag", doc.etag())
+	w.Header().Set("Taler-Terms-Version", h.termsVersion(doc))
+	w.Header().Set("Content-Language", doc.language)
+	if !doc.modTime.IsZero() {
+		w.Header().Set("Last-Modified", doc.modTime.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, doc) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", doc.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(doc.content)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(doc.content)
+}
+
+// Handler serves legal documents such as the terms of service or the
+// privacy policy from a file system, e.g.
+//
+//	mux.Handle("/terms", tos.NewHandler(cfg, os.DirFS(termsdatahome)))
+//	mux.Handle("/privacy", tos.NewHandler(cfg, os.DirFS(policydatahome)))
+//
+// Documents are expected at <language>/0.<extension>. HTML and plain
+// text documents missing in a language are converted from <language>/0.md.
+// Responses are compressed according to Accept-Encoding, using a
+// precompressed <file>.gz for gzip if present.
+type Handler struct {
+	// The configuration
+	cfg TalerTosConfig
+
+	// The documents
+	fsys fs.FS
+
+	// Documents converted from Markdown
+	renders *renderCache
+
+	// Compressed documents
+	encodings *encodingCache
+
+	// Version of the documents
+	versions *versionCache
+}
+
+// Create a new handler serving documents from fsys,
+// which may be a DocumentStore.
+func NewHandler(cfg TalerTosConfig, fsys fs.FS) *Handler {
+	return &Handler{
+		cfg:       cfg,
+		fsys:      fsys,
+		renders:   newRenderCache(),
+		encodings: newEncodingCache(),
+		versions:  newVersionCache(),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	h.serveDocument(w, r)
+}
+
+// A handler created by directoryHandler
+type directoryHandlerEntry struct {
+	cfg     TalerTosConfig
+	handler *Handler
+}
+
+// Handlers of ServiceTermsResponse and PrivacyPolicyResponse by
+// directory, so that their caches survive between requests
+var directoryHandlers = struct {
+	mu      sync.Mutex
+	entries map[string]*directoryHandlerEntry
+}{entries: make(map[string]*directoryHandlerEntry)}
+
+func sameConfig(a, b *TalerTosConfig) bool {
+	return a.DefaultFileType == b.DefaultFileType &&
+		slices.Equal(a.SupportedFileTypes, b.SupportedFileTypes) &&
+		a.DefaultLanguage == b.DefaultLanguage &&
+		a.TermsVersion == b.TermsVersion &&
+		a.Logger == b.Logger
+}
+
+// Returns the handler for dir, creating a new one if there is none
+// yet or the configuration changed
+func directoryHandler(dir string, cfg TalerTosConfig) *Handler {
+	directoryHandlers.mu.Lock()
+	defer directoryHandlers.mu.Unlock()
+	entry, ok := directoryHandlers.entries[dir]
+	if !ok || !sameConfig(&entry.cfg, &cfg) {
+		entry = &directoryHandlerEntry{
+			cfg:     cfg,
+			handler: NewHandler(cfg, os.DirFS(dir)),
 		}
+		entry.cfg.SupportedFileTypes = slices.Clone(cfg.SupportedFileTypes)
+		directoryHandlers.entries[dir] = entry
 	}
-	w.WriteHeader(http.StatusNotFound)
+	return entry.handler
 }
 
+func ServiceTermsResponse(w http.ResponseWriter, r *http.Request, termsdatahome string, cfg TalerTosConfig) {
+	directoryHandler(termsdatahome, cfg).ServeHTTP(w, r)
+}
 
 func PrivacyPolicyResponse(w http.ResponseWriter, r *http.Request, policydatahome string, cfg TalerTosConfig) {
-	fileType := cfg.DefaultFileType
-	termsLocation := policydatahome
-  for _, typ := range r.Header["Accept"] {
-    for _, a := range cfg.SupportedFileTypes {
-      if typ == a {
-        fileType = a
-      }
-    }
-  }
-
-  if len(r.Header.Get("Accept-Language")) != 0 {
-    acceptLangs, _, _ := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
-    for _, lang := range acceptLangs {
-      extensions, _ := mime.ExtensionsByType(fileType)
-      for _, ext := range extensions {
-        docFile := fmt.Sprintf("%s/%s/0%s", termsLocation, lang.String(), ext)
-        log.Printf("Trying %s\n", docFile)
-        fileBytes, err := os.ReadFile(docFile)
-        if nil == err {
-          w.Header().Set("Content-Type", fileType)
-          w.Write(fileBytes)
-          return
-        }
-      }
-    }
-  }
-  // Default document in expected/default format
-  defaultLanguage := cfg.DefaultLanguage
-  extensions, _ := mime.ExtensionsByType(fileType)
-  for _, ext := range extensions {
-    docFile := fmt.Sprintf("%s/%s/0%s", termsLocation, defaultLanguage, ext)
-    fileBytes, err := os.ReadFile(docFile)
-    if nil == err {
-      w.Header().Set("Content-Type", fileType)
-      w.Write(fileBytes)
-      return
-    }
-  }
-  w.WriteHeader(http.StatusNotFound)
+	directoryHandler(policydatahome, cfg).ServeHTTP(w, r)
 }
@@ -0,0 +1,563 @@
+package tos
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/schanzen/taler-go/internal/sqltest"
+)
+
+var testDocs = fstest.MapFS{
+	"en/0.html": &fstest.MapFile{Data: []byte("<p>Terms</p>"), ModTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+	"de/0.html": &fstest.MapFile{Data: []byte("<p>AGB</p>"), ModTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+}
+
+var testCfg = TalerTosConfig{
+	DefaultFileType:    "text/html",
+	SupportedFileTypes: []string{"text/html"},
+	DefaultLanguage:    "en",
+}
+
+func TestTermsConditional(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+	r.Header.Set("Accept-Language", "de")
+	w := httptest.NewRecorder()
+	NewHandler(testCfg, testDocs).ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "<p>AGB</p>" {
+		t.Fatalf("Unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Language") != "de" {
+		t.Errorf("Unexpected Content-Language %s", w.Header().Get("Content-Language"))
+	}
+	if w.Header().Get("Taler-Terms-Version") != "20260102030405" {
+		t.Errorf("Unexpected version %s", w.Header().Get("Taler-Terms-Version"))
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("ETag missing")
+	}
+
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	NewHandler(testCfg, testDocs).ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected 304, got %d", w.Code)
+	}
+
+	r.Header.Del("Accept-Language")
+	w = httptest.NewRecorder()
+	NewHandler(testCfg, testDocs).ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "<p>Terms</p>" {
+		t.Errorf("Unexpected response %d: %s", w.Code, w.Body.String())
+	}
+
+	r.Header.Del("If-None-Match")
+	r.Header.Set("If-Modified-Since", w.Header().Get("Last-Modified"))
+	w = httptest.NewRecorder()
+	NewHandler(testCfg, testDocs).ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304, got %d", w.Code)
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	supported := []string{"text/plain", "text/html", "application/pdf"}
+	for accept, expected := range map[string]string{
+		"":                                      "text/plain",
+		"text/html, text/plain;q=0.8":           "text/html",
+		"text/*":                                "text/plain",
+		"application/pdf, */*;q=0.1":            "application/pdf",
+		"*/*":                                   "text/plain",
+		"text/plain;q=0.2, text/*;q=0.5":        "text/html",
+		"text/html;level=1, application/pdf":    "text/html",
+		"image/png":                             "",
+		"text/*, text/plain;q=0, text/html;q=0": "",
+	} {
+		var headers []string
+		if accept != "" {
+			headers = []string{accept}
+		}
+		typ, ok := negotiateContentType(headers, supported, "text/plain")
+		if typ != expected || ok != (expected != "") {
+			t.Errorf("Unexpected type %s for %s", typ, accept)
+		}
+	}
+}
+
+func TestTermsNotAcceptable(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	NewHandler(testCfg, testDocs).ServeHTTP(w, r)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected 406, got %d", w.Code)
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	langs := availableLanguages(testDocs, "en")
+	if len(langs) != 2 || langs[0] != "en" || langs[1] != "de" {
+		t.Fatalf("Unexpected languages %v", langs)
+	}
+	for accept, expected := range map[string]string{
+		"de-CH":           "de",
+		"fr, de;q=0.5":    "de",
+		"en-US, de;q=0.9": "en",
+		"gsw":             "de",
+	} {
+		lang, ok := matchLanguage(accept, langs)
+		if !ok || lang != expected {
+			t.Errorf("Unexpected language %s for %s", lang, accept)
+		}
+	}
+	_, ok := matchLanguage("ja", langs)
+	if ok {
+		t.Errorf("Unexpected match for ja")
+	}
+	r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+	r.Header.Set("Accept-Language", "de-CH")
+	w := httptest.NewRecorder()
+	NewHandler(testCfg, testDocs).ServeHTTP(w, r)
+	if w.Body.String() != "<p>AGB</p>" || w.Header().Get("Avail-Languages") != "en, de" {
+		t.Errorf("Unexpected response %s (%s)", w.Body.String(), w.Header().Get("Avail-Languages"))
+	}
+}
+
+func TestDocumentStore(t *testing.T) {
+	docs := fstest.MapFS{
+		"en/0.html": &fstest.MapFile{Data: []byte("<p>Terms</p>")},
+	}
+	store, err := NewDocumentStore(docs)
+	if err != nil {
+		t.Fatalf("Failed creating store: %v", err)
+	}
+	err = fstest.TestFS(store, "en/0.html")
+	if err != nil {
+		t.Errorf("Store is not a valid file system: %v", err)
+	}
+	docs["en/0.html"] = &fstest.MapFile{Data: []byte("<p>New terms</p>"), ModTime: time.Now()}
+	content, _ := store.ReadFile("en/0.html")
+	if string(content) != "<p>Terms</p>" {
+		t.Errorf("Store not serving from cache")
+	}
+	reloaded, err := store.ReloadIfChanged()
+	if err != nil || !reloaded {
+		t.Errorf("Change not detected: %v", err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+	w := httptest.NewRecorder()
+	NewHandler(testCfg, store).ServeHTTP(w, r)
+	if w.Body.String() != "<p>New terms</p>" {
+		t.Errorf("Unexpected response %s", w.Body.String())
+	}
+	reloaded, _ = store.ReloadIfChanged()
+	if reloaded {
+		t.Errorf("Unexpected reload")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(testCfg, testDocs)
+	r := httptest.NewRequest(http.MethodHead, "/terms", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "12" {
+		t.Errorf("Unexpected HEAD response %d (%s)", w.Code, w.Header().Get("Content-Length"))
+	}
+	r = httptest.NewRequest(http.MethodPost, "/terms", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+}
+
+func TestAcceptanceChecker(t *testing.T) {
+	checker := AcceptanceChecker{
+		Store:        NewMemoryAcceptanceStore(),
+		TermsVersion: "v1",
+		AccountID: func(r *http.Request) (string, bool) {
+			id := r.Header.Get("X-Account")
+			return id, id != ""
+		},
+	}
+	h := checker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	check := func(account string, expected int) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Account", account)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("Expected %d for %s, got %d", expected, account, w.Code)
+		}
+	}
+	check("", http.StatusUnauthorized)
+	check("alice", http.StatusForbidden)
+	checker.Accept(context.Background(), "alice", "en")
+	check("alice", http.StatusNoContent)
+	checker.TermsVersion = "v2"
+	check("alice", http.StatusForbidden)
+}
+
+// Emulates an acceptance table. Queries fail once fail is set.
+type testAcceptanceDB struct {
+	mu      sync.Mutex
+	created bool
+	fail    bool
+	rows    [][]driver.Value
+}
+
+func (db *testAcceptanceDB) exec(query string, args []driver.Value) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS acceptances\n"):
+		db.created = true
+	case strings.HasPrefix(query, "INSERT INTO acceptances "):
+		if !db.created {
+			return errors.New("relation does not exist")
+		}
+		db.rows = append(db.rows, args)
+	default:
+		return errors.New("unexpected statement " + query)
+	}
+	return nil
+}
+
+func (db *testAcceptanceDB) query(query string, args []driver.Value) (*sqltest.Rows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.fail {
+		return nil, errors.New("connection to server lost")
+	}
+	if !strings.HasPrefix(query, "SELECT account_id, terms_version, language, accepted_at FROM acceptances ") {
+		return nil, errors.New("unexpected query " + query)
+	}
+	rows := &sqltest.Rows{Columns: []string{"account_id", "terms_version", "language", "accepted_at"}}
+	// Rows are inserted in serial order, the last one with the
+	// highest accepted_at wins
+	var latest []driver.Value
+	for _, row := range db.rows {
+		if row[0] == args[0] && (latest == nil || row[3].(int64) >= latest[3].(int64)) {
+			latest = row
+		}
+	}
+	if latest != nil {
+		rows.Values = [][]driver.Value{latest}
+	}
+	return rows, nil
+}
+
+func openTestAcceptanceStore(t *testing.T) (*SQLAcceptanceStore, *testAcceptanceDB) {
+	tdb := &testAcceptanceDB{}
+	db := sqltest.Open(t, &sqltest.DB{Exec: tdb.exec, Query: tdb.query})
+	store, err := NewSQLAcceptanceStore(db, "acceptances")
+	if err != nil {
+		t.Fatalf("Failed creating store: %v", err)
+	}
+	return store, tdb
+}
+
+func TestSQLAcceptanceStore(t *testing.T) {
+	_, err := NewSQLAcceptanceStore(nil, "x; DROP TABLE y")
+	if nil == err {
+		t.Errorf("Invalid table name accepted")
+	}
+	for _, table := range []string{"acceptances", "merchant.acceptances"} {
+		if _, err := NewSQLAcceptanceStore(nil, table); err != nil {
+			t.Errorf("Failed creating store for %s: %v", table, err)
+		}
+	}
+
+	store, _ := openTestAcceptanceStore(t)
+	ctx := context.Background()
+	if err := store.CreateTable(ctx); err != nil {
+		t.Fatalf("Failed creating table: %v", err)
+	}
+	if _, err := store.LatestAcceptance(ctx, "alice"); !errors.Is(err, ErrNotAccepted) {
+		t.Errorf("Expected ErrNotAccepted, got %v", err)
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)
+	for _, a := range []Acceptance{
+		{AccountID: "alice", TermsVersion: "v1", Language: "en", AcceptedAt: at},
+		{AccountID: "alice", TermsVersion: "v2", Language: "de", AcceptedAt: at.Add(time.Hour)},
+		{AccountID: "bob", TermsVersion: "v3", Language: "en", AcceptedAt: at.Add(2 * time.Hour)},
+	} {
+		if err := store.RecordAcceptance(ctx, a); err != nil {
+			t.Fatalf("Failed recording acceptance: %v", err)
+		}
+	}
+	a, err := store.LatestAcceptance(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Failed reading acceptance: %v", err)
+	}
+	if a.AccountID != "alice" || a.TermsVersion != "v2" || a.Language != "de" || !a.AcceptedAt.Equal(at.Add(time.Hour)) {
+		t.Errorf("Unexpected acceptance %+v", a)
+	}
+
+	checker := AcceptanceChecker{
+		Store:        store,
+		TermsVersion: "v2",
+		AccountID: func(r *http.Request) (string, bool) {
+			id := r.Header.Get("X-Account")
+			return id, id != ""
+		},
+	}
+	accepted, err := checker.HasAccepted(ctx, "alice")
+	if err != nil || !accepted {
+		t.Errorf("Failed checking acceptance: %v %v", accepted, err)
+	}
+	accepted, err = checker.HasAccepted(ctx, "bob")
+	if err != nil || accepted {
+		t.Errorf("Failed checking outdated acceptance: %v %v", accepted, err)
+	}
+}
+
+func TestAcceptanceCheckerErrors(t *testing.T) {
+	store, tdb := openTestAcceptanceStore(t)
+	var logs bytes.Buffer
+	checker := AcceptanceChecker{
+		Store:        store,
+		TermsVersion: "v1",
+		AccountID: func(r *http.Request) (string, bool) {
+			id := r.Header.Get("X-Account")
+			return id, id != ""
+		},
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+	store.CreateTable(context.Background())
+	h := checker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	check := func(account string, status int, code int) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Account", account)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		var talerErr TalerError
+		if err := json.Unmarshal(w.Body.Bytes(), &talerErr); err != nil {
+			t.Fatalf("Failed decoding error: %v", err)
+		}
+		if w.Code != status || talerErr.Code != code {
+			t.Errorf("Expected %d/%d for %s, got %d/%d", status, code, account, w.Code, talerErr.Code)
+		}
+		if strings.Contains(w.Body.String(), "connection to server lost") {
+			t.Errorf("Database error leaked: %s", w.Body.String())
+		}
+	}
+	check("", http.StatusUnauthorized, ErrorCodeGenericUnauthorized)
+	check("alice", http.StatusForbidden, ErrorCodeGenericForbidden)
+	checker.ErrorCode = 1234
+	check("alice", http.StatusForbidden, 1234)
+	tdb.fail = true
+	check("alice", http.StatusInternalServerError, ErrorCodeGenericDBFetchFailed)
+	if !strings.Contains(logs.String(), "connection to server lost") {
+		t.Errorf("Database error not logged: %s", logs.String())
+	}
+}
+
+func TestMarkdownConversion(t *testing.T) {
+	src := []byte("# Terms\n\nPlease read *carefully*.\nSee [GNU Taler](https://taler.net) and `code`.\n\n- one\n- **two**\n\n---\n")
+	html := string(markdownToHTML(src))
+	for _, expected := range []string{
+		"<h1>Terms</h1>",
+		"<p>Please read <em>carefully</em>.\nSee <a href=\"https://taler.net\">GNU Taler</a> and <code>code</code>.</p>",
+		"<ul>\n<li>one</li>\n<li><strong>two</strong></li>\n</ul>",
+		"<hr>",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Missing %s in:\n%s", expected, html)
+		}
+	}
+	text := string(markdownToText(src))
+	expected := "Terms\n=====\n\nPlease read carefully.\nSee GNU Taler (https://taler.net) and code.\n\n- one\n- two\n\n" + strings.Repeat("-", 72) + "\n"
+	if text != expected {
+		t.Errorf("Unexpected text:\n%s", text)
+	}
+
+	docs := fstest.MapFS{
+		"en/0.md": &fstest.MapFile{Data: src},
+	}
+	cfg := testCfg
+	cfg.SupportedFileTypes = []string{"text/html", "text/plain"}
+	h := NewHandler(cfg, docs)
+	r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+	r.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != expected {
+		t.Errorf("Unexpected response %d:\n%s", w.Code, w.Body.String())
+	}
+}
+
+func TestCompression(t *testing.T) {
+	if negotiateEncoding("deflate, gzip;q=0.5") != "deflate" ||
+		negotiateEncoding("*") != "gzip" ||
+		negotiateEncoding("br, identity") != "" ||
+		negotiateEncoding("gzip;q=0, *") != "deflate" {
+		t.Errorf("Unexpected encoding negotiation")
+	}
+	docs := fstest.MapFS{
+		"en/0.html":    &fstest.MapFile{Data: []byte("<p>Terms</p>")},
+		"de/0.html":    &fstest.MapFile{Data: []byte("<p>AGB</p>")},
+		"de/0.html.gz": &fstest.MapFile{Data: []byte("precompressed")},
+	}
+	h := NewHandler(testCfg, docs)
+	r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept, Accept-Language, Accept-Encoding" {
+		t.Fatalf("Unexpected headers %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip response: %v", err)
+	}
+	content, _ := io.ReadAll(zr)
+	if string(content) != "<p>Terms</p>" {
+		t.Errorf("Unexpected content %s", content)
+	}
+	r.Header.Set("Accept-Language", "de")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Body.String() != "precompressed" {
+		t.Errorf("Precompressed file not used")
+	}
+	r.Header.Del("Accept-Encoding")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Body.String() != "<p>AGB</p>" || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Unexpected uncompressed response %s", w.Body.String())
+	}
+
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	docs = fstest.MapFS{
+		"de/0.html":    &fstest.MapFile{Data: []byte("<p>AGB</p>"), ModTime: modTime},
+		"de/0.html.gz": &fstest.MapFile{Data: []byte("stale"), ModTime: modTime.Add(-time.Hour)},
+	}
+	h = NewHandler(testCfg, docs)
+	r.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	zr, err = gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Stale precompressed file used: %v", err)
+	}
+	content, _ = io.ReadAll(zr)
+	if string(content) != "<p>AGB</p>" {
+		t.Errorf("Unexpected content %s", content)
+	}
+}
+
+func TestTermsVersionShared(t *testing.T) {
+	docs := fstest.MapFS{
+		"en/0.html": &fstest.MapFile{Data: []byte("<p>Terms</p>"), ModTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		"de/0.md":   &fstest.MapFile{Data: []byte("AGB"), ModTime: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	h := NewHandler(testCfg, docs)
+	for _, lang := range []string{"en", "de"} {
+		r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Header().Get("Content-Language") != lang || w.Header().Get("Taler-Terms-Version") != "20260301000000" {
+			t.Errorf("Unexpected version %s for %s", w.Header().Get("Taler-Terms-Version"), w.Header().Get("Content-Language"))
+		}
+	}
+}
+
+// Counts how often directories are opened
+type countingFS struct {
+	fstest.MapFS
+	opens map[string]int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opens[name]++
+	return c.MapFS.Open(name)
+}
+
+func TestTermsVersionCached(t *testing.T) {
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	docs := fstest.MapFS{
+		"en/0.html": &fstest.MapFile{Data: []byte("<p>Terms</p>"), ModTime: modTime},
+		"de/0.html": &fstest.MapFile{Data: []byte("<p>AGB</p>"), ModTime: modTime},
+	}
+	cfs := &countingFS{docs, make(map[string]int)}
+	h := NewHandler(testCfg, struct{ fs.FS }{cfs})
+	version := func(method string) string {
+		r := httptest.NewRequest(method, "/terms", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Header().Get("Taler-Terms-Version")
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodGet} {
+		if v := version(method); v != "20260102030405" {
+			t.Errorf("Unexpected version %s", v)
+		}
+	}
+	if cfs.opens["de"] != 1 {
+		t.Errorf("Documents walked %d times", cfs.opens["de"])
+	}
+	docs["en/0.html"].ModTime = modTime.Add(time.Hour)
+	if v := version(http.MethodGet); v != "20260102040405" || cfs.opens["de"] != 2 {
+		t.Errorf("Unexpected version %s after change", v)
+	}
+
+	store, err := NewDocumentStore(docs)
+	if err != nil {
+		t.Fatalf("Failed creating store: %v", err)
+	}
+	h = NewHandler(testCfg, store)
+	if v := version(http.MethodGet); v != "20260102040405" {
+		t.Errorf("Unexpected store version %s", v)
+	}
+	docs["de/0.html"].ModTime = modTime.Add(2 * time.Hour)
+	store.Reload()
+	if v := version(http.MethodGet); v != "20260102050405" {
+		t.Errorf("Unexpected store version %s after reload", v)
+	}
+}
+
+func TestServiceTermsResponse(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "en"), 0755)
+	os.WriteFile(filepath.Join(dir, "en", "0.md"), []byte("# Terms\n"), 0644)
+	cfg := testCfg
+	cfg.SupportedFileTypes = []string{"text/html"}
+	for range 2 {
+		r := httptest.NewRequest(http.MethodGet, "/terms", nil)
+		w := httptest.NewRecorder()
+		ServiceTermsResponse(w, r, dir, cfg)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<h1>Terms</h1>") {
+			t.Errorf("Unexpected response %d: %s", w.Code, w.Body.String())
+		}
+	}
+	h := directoryHandler(dir, cfg)
+	if len(h.renders.entries) != 1 {
+		t.Errorf("Rendered document not cached")
+	}
+	cfg.SupportedFileTypes[0] = "text/plain"
+	if directoryHandler(dir, cfg) == h {
+		t.Errorf("Handler kept for changed configuration")
+	}
+}
@@ -19,6 +19,7 @@
 package util
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -195,7 +196,7 @@ func ParseAmount(s string) (*Amount, error) {
 	if len(parsed) >= 4 {
 		tail = "0." + parsed[3]
 	}
-	if len(tail) > FractionalLength+1 {
+	if len(tail) > FractionalLength+2 {
 		return nil, errors.New("fraction too long")
 	}
 	value, err := strconv.ParseUint(parsed[2], 10, 64)
@@ -213,18 +214,42 @@ func ParseAmount(s string) (*Amount, error) {
 }
 
 // Check if this amount is zero
-func (a *Amount) IsZero() bool {
+func (a Amount) IsZero() bool {
 	return (a.Value == 0) && (a.Fraction == 0)
 }
 
 // Returns the string representation of the amount: <currency>:<value>[.<fraction>]
 // Omits trailing zeroes.
-func (a *Amount) String() string {
+func (a Amount) String() string {
 	v := strconv.FormatUint(a.Value, 10)
 	if a.Fraction != 0 {
-		f := strconv.FormatUint(a.Fraction, 10)
+		f := fmt.Sprintf("%0*d", FractionalLength, a.Fraction)
 		f = strings.TrimRight(f, "0")
 		v = fmt.Sprintf("%s.%s", v, f)
 	}
 	return fmt.Sprintf("%s:%s", a.Currency, v)
 }
+
+// Encodes the amount as JSON string
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// Decodes the amount from a JSON string.
+// Values of MaxAmountValue and above are rejected.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	if parsed.Value >= MaxAmountValue {
+		return fmt.Errorf("amount value %d too large", parsed.Value)
+	}
+	*a = *parsed
+	return nil
+}
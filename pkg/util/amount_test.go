@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 )
@@ -84,3 +85,44 @@ func TestAmountFormat(t *testing.T) {
 		t.Errorf("Failed")
 	}
 }
+
+func TestAmountString(t *testing.T) {
+	for _, s := range []string{"EUR:0.01", "EUR:1.5", "EUR:3", "EUR:0.00000001", "KUDOS:10.10203"} {
+		x, err := ParseAmount(s)
+		if err != nil {
+			t.Errorf("Failed parsing %s", s)
+			continue
+		}
+		if x.String() != s {
+			t.Errorf("Expected %s, got %s", s, x.String())
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	var x struct {
+		Price Amount `json:"price"`
+	}
+	err := json.Unmarshal([]byte(`{"price": "EUR:1.05"}`), &x)
+	if err != nil || x.Price.Value != 1 || x.Price.Fraction != 5000000 {
+		t.Errorf("Failed decoding amount: %v", err)
+	}
+	b, _ := json.Marshal(x)
+	if string(b) != `{"price":"EUR:1.05"}` {
+		t.Errorf("Unexpected JSON %s", b)
+	}
+	for _, invalid := range []string{`"EUR"`, `"EUR:4503599627370496"`, `"EUR:4503599627370497"`} {
+		err = json.Unmarshal([]byte(`{"price": `+invalid+`}`), &x)
+		if nil == err {
+			t.Errorf("Invalid amount %s accepted", invalid)
+		}
+	}
+	err = json.Unmarshal([]byte(`{"price": "EUR:4503599627370495.99999999"}`), &x)
+	if err != nil {
+		t.Errorf("Failed decoding maximum amount: %v", err)
+	}
+	prices := map[string]Amount{"EUR": x.Price}
+	if prices["EUR"].String() != "EUR:4503599627370495.99999999" {
+		t.Errorf("Unexpected amount %s", prices["EUR"].String())
+	}
+}
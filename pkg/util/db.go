@@ -20,10 +20,14 @@ package util
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 )
 
 func CheckVersioning(db *sql.DB) (bool, error) {
@@ -33,7 +37,7 @@ func CheckVersioning(db *sql.DB) (bool, error) {
 	}
 	defer rows.Close()
 	if rows.Next() {
-		fmt.Println("Versioning applied")
+		Logger().Debug("versioning applied")
 		return true, nil
 	}
 	return false, nil
@@ -51,49 +55,172 @@ func CheckPatch(db *sql.DB, patchName string) (bool, error) {
 	return false, nil
 }
 
+// Runs SQL files for Migrator, DBDowngrade and DBReset, replaced in tests
+var runSQL = RunSQL
+
 func RunSQL(db *sql.DB, patchName string, dbName string) error {
 	path, err := exec.LookPath("psql")
 	if err != nil {
 		return err
 	}
+	Logger().Debug("running psql", "psql", path, "file", patchName)
 	_, err = exec.Command(path, dbName, "-f", patchName, "-q", "--set", "ON_ERROR_STOP=1").Output()
-	fmt.Printf("Running: %s %s %s %s %s %s %s\n", path, dbName, "-f", patchName, "-q", "--set", "ON_ERROR_STOP=1")
 	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			Logger().Error("psql failed", "file", patchName, "stderr", string(exitErr.Stderr))
+		}
 		return err
 	}
 	return nil
 
 }
 
+// Applies versioning.sql and all patches with the given prefix from
+// the sql directory in datahome. See Migrator for multiple components.
 func DBInit(db *sql.DB, datahome string, dbName string, patchesPrefix string) error {
+	m := Migrator{
+		DB:     db,
+		DBName: dbName,
+		Components: []DBComponent{
+			{
+				Prefix:   patchesPrefix,
+				DataHome: datahome,
+			},
+		},
+	}
+	return m.Migrate()
+}
+
+// Returns the number of the patch if patchName is <prefix>-NNNN
+func patchNumber(patchName string, patchesPrefix string) (int, bool) {
+	num, found := strings.CutPrefix(patchName, patchesPrefix+"-")
+	if !found || len(num) != 4 {
+		return 0, false
+	}
+	for _, c := range num {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(num)
+	return n, err == nil
+}
+
+// Returns the names of all patches with the given prefix that are
+// registered in _v.patches, most recent first. Patches of other
+// components sharing the prefix (e.g. <prefix>-ext-0001) are not included.
+func AppliedPatches(db *sql.DB, patchesPrefix string) ([]string, error) {
+	rows, err := db.Query(`SELECT patch_name FROM _v.patches;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var patches []string
+	for rows.Next() {
+		var patchName string
+		if err := rows.Scan(&patchName); err != nil {
+			return nil, err
+		}
+		if _, ok := patchNumber(patchName, patchesPrefix); ok {
+			patches = append(patches, patchName)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(patches, func(a, b string) int {
+		return strings.Compare(b, a)
+	})
+	return patches, nil
+}
+
+func UnregisterPatch(db *sql.DB, patchName string) error {
+	_, err := db.Exec(`SELECT _v.unregister_patch($1);`, patchName)
+	return err
+}
+
+// Reverts all patches with the given prefix newer than patch number "to"
+// by running their down files (<prefix>-<NNNN>-down.sql) in reverse order.
+// Patches that are still registered after their down file ran are
+// unregistered using _v.unregister_patch.
+// Nothing is run if any of the required down files is missing.
+func DBDowngrade(db *sql.DB, datahome string, dbName string, patchesPrefix string, to int) error {
 	applied, err := CheckVersioning(db)
-	loadSuffix := filepath.Join(datahome, "sql")
 	if err != nil {
-		fmt.Printf("%v\n", err)
+		return err
 	}
 	if !applied {
-		err := RunSQL(db, filepath.Join(loadSuffix, "versioning.sql"), dbName)
-		if err != nil {
-			return err
+		Logger().Info("versioning not applied, nothing to downgrade")
+		return nil
+	}
+	loadSuffix := filepath.Join(datahome, "sql")
+	patches, err := AppliedPatches(db, patchesPrefix)
+	if err != nil {
+		return err
+	}
+	var downFiles []string
+	var downPatches []string
+	for _, patchName := range patches {
+		num, _ := patchNumber(patchName, patchesPrefix)
+		if num <= to {
+			continue
 		}
+		downFile := fmt.Sprintf("%s-down.sql", filepath.Join(loadSuffix, patchName))
+		if _, err := os.Stat(downFile); err != nil {
+			return fmt.Errorf("cannot downgrade patch %s: %w", patchName, err)
+		}
+		downFiles = append(downFiles, downFile)
+		downPatches = append(downPatches, patchName)
 	}
-	for i := range 10000 {
-		patchName := fmt.Sprintf("%s-%04d", patchesPrefix, i+1)
-		applied, err := CheckPatch(db, patchName)
+	for i, patchName := range downPatches {
+		Logger().Info("reverting patch", "patch", patchName, "file", downFiles[i])
+		err = runSQL(db, downFiles[i], dbName)
 		if err != nil {
 			return err
 		}
-		if applied {
-			fmt.Printf("Patch %s already applied\n", patchName)
-			continue
+		stillApplied, err := CheckPatch(db, patchName)
+		if err != nil {
+			return err
 		}
-		patchFile := fmt.Sprintf("%s.sql", filepath.Join(loadSuffix, patchName))
-		if _, err := os.Stat(patchFile); err != nil {
-			fmt.Printf("Patch %s not found, up-to-date.\n", patchFile)
-			break
+		if stillApplied {
+			err = UnregisterPatch(db, patchName)
+			if err != nil {
+				return err
+			}
 		}
-		fmt.Printf("Applying patch %s\n", patchName)
-		err = RunSQL(db, patchFile, dbName)
+	}
+	return nil
+}
+
+// Tears down everything DBInit created for the given prefix.
+// If a <prefix>-drop.sql exists it is run, otherwise all patches are
+// reverted using their down files as in DBDowngrade. The drop file must
+// only drop objects of its own component, as other components may
+// share the database.
+func DBReset(db *sql.DB, datahome string, dbName string, patchesPrefix string) error {
+	dropFile := filepath.Join(datahome, "sql", patchesPrefix+"-drop.sql")
+	if _, err := os.Stat(dropFile); err != nil {
+		Logger().Info("drop file not found, reverting patches", "file", dropFile)
+		return DBDowngrade(db, datahome, dbName, patchesPrefix, 0)
+	}
+	Logger().Info("dropping schema", "file", dropFile)
+	err := runSQL(db, dropFile, dbName)
+	if err != nil {
+		return err
+	}
+	applied, err := CheckVersioning(db)
+	if err != nil || !applied {
+		return err
+	}
+	// The drop file is expected to unregister its patches, clean up
+	// whatever it left behind.
+	patches, err := AppliedPatches(db, patchesPrefix)
+	if err != nil {
+		return err
+	}
+	for _, patchName := range patches {
+		err = UnregisterPatch(db, patchName)
 		if err != nil {
 			return err
 		}
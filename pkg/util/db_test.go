@@ -0,0 +1,161 @@
+package util
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/schanzen/taler-go/internal/sqltest"
+)
+
+// Emulates the _v.patches table
+type testPatches struct {
+	mu        sync.Mutex
+	versioned bool
+	names     []string
+}
+
+var rexTestPatchFile = regexp.MustCompile(`^[a-z-]+-[0-9]{4}\.sql$`)
+
+func (p *testPatches) exec(query string, args []driver.Value) error {
+	if !strings.Contains(query, "_v.unregister_patch") {
+		return errors.New("unexpected statement " + query)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.names = slices.DeleteFunc(p.names, func(n string) bool {
+		return n == args[0].(string)
+	})
+	return nil
+}
+
+func (p *testPatches) query(query string, args []driver.Value) (*sqltest.Rows, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var values []string
+	switch {
+	case strings.Contains(query, "information_schema"):
+		if p.versioned {
+			values = []string{"_v"}
+		}
+	case strings.Contains(query, "applied_by"):
+		if slices.Contains(p.names, args[0].(string)) {
+			values = []string{"test"}
+		}
+	case strings.Contains(query, "SELECT patch_name"):
+		values = p.names
+	default:
+		return nil, errors.New("unexpected query " + query)
+	}
+	rows := &sqltest.Rows{Columns: []string{"value"}}
+	for _, v := range values {
+		rows.Values = append(rows.Values, []driver.Value{v})
+	}
+	return rows, nil
+}
+
+// Opens a test database with the given patches applied and records
+// the SQL files run instead of calling psql. Running versioning.sql
+// or a patch file registers it like the real files do.
+func openTestPatchDB(t *testing.T, patches ...string) (*sql.DB, *testPatches, *[]string) {
+	p := &testPatches{versioned: true, names: patches}
+	db := sqltest.Open(t, &sqltest.DB{Exec: p.exec, Query: p.query})
+	var ran []string
+	orig := runSQL
+	runSQL = func(db *sql.DB, file string, dbName string) error {
+		name := filepath.Base(file)
+		ran = append(ran, name)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if name == "versioning.sql" {
+			p.versioned = true
+		} else if patch, ok := strings.CutSuffix(name, ".sql"); ok && rexTestPatchFile.MatchString(name) {
+			p.names = append(p.names, patch)
+		}
+		return nil
+	}
+	t.Cleanup(func() { runSQL = orig })
+	return db, p, &ran
+}
+
+func writeSQLFiles(t *testing.T, files ...string) string {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sql"), 0755)
+	for _, f := range files {
+		os.WriteFile(filepath.Join(dir, "sql", f), []byte{}, 0644)
+	}
+	return dir
+}
+
+func TestAppliedPatches(t *testing.T) {
+	db, _, _ := openTestPatchDB(t, "merchant-0001", "merchant-ext-0001", "merchant-0003", "merchantx0002", "merchant-0002", "merchant-00a1")
+	patches, err := AppliedPatches(db, "merchant")
+	if err != nil {
+		t.Fatalf("Failed listing patches: %v", err)
+	}
+	if !slices.Equal(patches, []string{"merchant-0003", "merchant-0002", "merchant-0001"}) {
+		t.Errorf("Unexpected patches %v", patches)
+	}
+	patches, _ = AppliedPatches(db, "merchant_")
+	if len(patches) != 0 {
+		t.Errorf("Prefix treated as pattern: %v", patches)
+	}
+}
+
+func TestDBDowngrade(t *testing.T) {
+	db, p, ran := openTestPatchDB(t, "merchant-0001", "merchant-0002", "merchant-0003", "merchant-ext-0001")
+	dir := writeSQLFiles(t, "merchant-0002-down.sql", "merchant-0003-down.sql")
+	err := DBDowngrade(db, dir, "test", "merchant", 1)
+	if err != nil {
+		t.Fatalf("Failed downgrading: %v", err)
+	}
+	if !slices.Equal(*ran, []string{"merchant-0003-down.sql", "merchant-0002-down.sql"}) {
+		t.Errorf("Unexpected down files %v", *ran)
+	}
+	if !slices.Equal(p.names, []string{"merchant-0001", "merchant-ext-0001"}) {
+		t.Errorf("Unexpected remaining patches %v", p.names)
+	}
+}
+
+func TestDBDowngradeMissingDownFile(t *testing.T) {
+	db, p, ran := openTestPatchDB(t, "merchant-0001", "merchant-0002")
+	dir := writeSQLFiles(t, "merchant-0002-down.sql")
+	err := DBDowngrade(db, dir, "test", "merchant", 0)
+	if nil == err {
+		t.Errorf("Missing down file not detected")
+	}
+	if len(*ran) != 0 || len(p.names) != 2 {
+		t.Errorf("Downgrade partially applied: %v %v", *ran, p.names)
+	}
+}
+
+func TestDBReset(t *testing.T) {
+	db, p, ran := openTestPatchDB(t, "merchant-0001", "merchant-0002", "merchant-ext-0001")
+	dir := writeSQLFiles(t, "merchant-drop.sql", "merchant-ext-drop.sql", "drop.sql")
+	err := DBReset(db, dir, "test", "merchant")
+	if err != nil {
+		t.Fatalf("Failed resetting: %v", err)
+	}
+	if !slices.Equal(*ran, []string{"merchant-drop.sql"}) || !slices.Equal(p.names, []string{"merchant-ext-0001"}) {
+		t.Errorf("Unexpected reset %v %v", *ran, p.names)
+	}
+}
+
+func TestDBResetWithoutDropFile(t *testing.T) {
+	db, p, ran := openTestPatchDB(t, "merchant-0001", "merchant-0002", "merchant-ext-0001")
+	dir := writeSQLFiles(t, "drop.sql", "merchant-0001-down.sql", "merchant-0002-down.sql")
+	err := DBReset(db, dir, "test", "merchant")
+	if err != nil {
+		t.Fatalf("Failed resetting: %v", err)
+	}
+	if !slices.Equal(*ran, []string{"merchant-0002-down.sql", "merchant-0001-down.sql"}) || !slices.Equal(p.names, []string{"merchant-ext-0001"}) {
+		t.Errorf("Unexpected reset %v %v", *ran, p.names)
+	}
+}
@@ -0,0 +1,115 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Source of configuration values. Use a *config.Config loaded with
+// config.Load, which handles the @inline@ directives (packaged installs
+// usually keep the database CONFIG in a file pulled in with
+// @inline-secret@).
+type ConfigSource interface {
+	// Returns the value of option in section
+	GetString(section string, option string) (string, error)
+}
+
+// The Postgres database configuration of a Taler component
+type DBConfig struct {
+	// The configuration section, e.g. "merchantdb-postgres"
+	Section string
+
+	// The connection URI, e.g. "postgres:///taler-merchant"
+	URI string
+}
+
+// Returns the name of the Postgres section of a component,
+// e.g. "merchantdb-postgres" for "merchant".
+func PostgresSection(component string) string {
+	return component + "db-postgres"
+}
+
+// Reads the CONFIG option of the given section, e.g.
+//
+//	cfg, err := config.Load(defaultsDir, "/etc/taler/taler.conf")
+//	...
+//	dbCfg, err := util.LoadDBConfig(cfg, util.PostgresSection("merchant"))
+func LoadDBConfig(cfg ConfigSource, section string) (*DBConfig, error) {
+	uri, err := cfg.GetString(section, "CONFIG")
+	if err != nil {
+		return nil, err
+	}
+	if uri == "" {
+		return nil, fmt.Errorf("option CONFIG in section [%s] is empty", section)
+	}
+	return &DBConfig{
+		Section: section,
+		URI:     uri,
+	}, nil
+}
+
+// Returns the database name from the URI
+func (c *DBConfig) DBName() (string, error) {
+	u, err := url.Parse(c.URI)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return "", errors.New("not a postgres URI: " + c.URI)
+	}
+	if name := u.Query().Get("dbname"); name != "" {
+		return name, nil
+	}
+	return strings.TrimPrefix(u.Path, "/"), nil
+}
+
+// Opens the database using the given driver, e.g. "postgres" for lib/pq
+// or "pgx" for the pgx stdlib driver
+func (c *DBConfig) Open(driverName string) (*sql.DB, error) {
+	return sql.Open(driverName, c.URI)
+}
+
+// Runs DBInit with the configured database
+func (c *DBConfig) Init(db *sql.DB, datahome string, patchesPrefix string) error {
+	return DBInit(db, datahome, c.URI, patchesPrefix)
+}
+
+// Returns a Migrator for the configured database
+func (c *DBConfig) Migrator(db *sql.DB, components []DBComponent) *Migrator {
+	return &Migrator{
+		DB:         db,
+		DBName:     c.URI,
+		Components: components,
+	}
+}
+
+// Runs DBDowngrade with the configured database
+func (c *DBConfig) Downgrade(db *sql.DB, datahome string, patchesPrefix string, to int) error {
+	return DBDowngrade(db, datahome, c.URI, patchesPrefix, to)
+}
+
+// Runs DBReset with the configured database
+func (c *DBConfig) Reset(db *sql.DB, datahome string, patchesPrefix string) error {
+	return DBReset(db, datahome, c.URI, patchesPrefix)
+}
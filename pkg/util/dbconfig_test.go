@@ -0,0 +1,43 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+)
+
+// ConfigSource backed by a map of "section.option" keys
+type testConfigSource map[string]string
+
+func (c testConfigSource) GetString(section string, option string) (string, error) {
+	value, ok := c[section+"."+option]
+	if !ok {
+		return "", fmt.Errorf("option %s missing in section [%s]", option, section)
+	}
+	return value, nil
+}
+
+func TestLoadDBConfig(t *testing.T) {
+	cfg := testConfigSource{
+		"merchantdb-postgres.CONFIG": "postgres:///taler-merchant",
+		"exchangedb-postgres.CONFIG": "",
+	}
+	c, err := LoadDBConfig(cfg, PostgresSection("merchant"))
+	if err != nil {
+		t.Fatalf("Failed loading config: %v", err)
+	}
+	if c.URI != "postgres:///taler-merchant" {
+		t.Errorf("Unexpected URI %s", c.URI)
+	}
+	name, err := c.DBName()
+	if err != nil || name != "taler-merchant" {
+		t.Errorf("Unexpected database name %s: %v", name, err)
+	}
+	_, err = LoadDBConfig(cfg, PostgresSection("exchange"))
+	if nil == err {
+		t.Errorf("Empty CONFIG not detected")
+	}
+	_, err = LoadDBConfig(cfg, PostgresSection("auditor"))
+	if nil == err {
+		t.Errorf("Missing section not detected")
+	}
+}
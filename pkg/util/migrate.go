@@ -0,0 +1,211 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A database component with its own set of patches
+type DBComponent struct {
+	// The patch prefix, e.g. "merchant" for merchant-0001.sql
+	Prefix string
+
+	// The data directory of the component. Patches are
+	// expected in its "sql" subdirectory.
+	DataHome string
+
+	// Prefixes of components that must be migrated before this one
+	DependsOn []string
+
+	// The highest patch number of this component.
+	// If zero, the patches are discovered from the sql directory.
+	// Missing patch numbers are skipped in either case.
+	MaxPatch int
+}
+
+// Applies the patches of several components to one database
+type Migrator struct {
+	// The database
+	DB *sql.DB
+
+	// The database name or connection URI passed to psql
+	DBName string
+
+	// The components to migrate
+	Components []DBComponent
+
+	// Logger to use, Logger() if nil
+	Logger *slog.Logger
+}
+
+func (m *Migrator) logger() *slog.Logger {
+	if m.Logger != nil {
+		return m.Logger
+	}
+	return Logger()
+}
+
+// Returns the components in dependency order. Components without
+// dependencies between each other keep their relative order.
+func (m *Migrator) orderedComponents() ([]DBComponent, error) {
+	byPrefix := make(map[string]DBComponent)
+	for _, c := range m.Components {
+		if _, dup := byPrefix[c.Prefix]; dup {
+			return nil, fmt.Errorf("duplicate component %s", c.Prefix)
+		}
+		byPrefix[c.Prefix] = c
+	}
+	for _, c := range m.Components {
+		for _, dep := range c.DependsOn {
+			if _, ok := byPrefix[dep]; !ok {
+				return nil, fmt.Errorf("component %s depends on unknown component %s", c.Prefix, dep)
+			}
+		}
+	}
+	var ordered []DBComponent
+	done := make(map[string]bool)
+	for len(ordered) < len(m.Components) {
+		progress := false
+		for _, c := range m.Components {
+			if done[c.Prefix] {
+				continue
+			}
+			ready := true
+			for _, dep := range c.DependsOn {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, c)
+				done[c.Prefix] = true
+				progress = true
+			}
+		}
+		if !progress {
+			return nil, fmt.Errorf("dependency cycle between components")
+		}
+	}
+	return ordered, nil
+}
+
+// Returns the patch numbers of the component in ascending order
+func (c *DBComponent) patchNumbers() ([]int, error) {
+	loadSuffix := filepath.Join(c.DataHome, "sql")
+	var numbers []int
+	if c.MaxPatch > 0 {
+		for i := range c.MaxPatch {
+			numbers = append(numbers, i+1)
+		}
+		return numbers, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(loadSuffix, c.Prefix+"-[0-9][0-9][0-9][0-9].sql"))
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range matches {
+		num := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), c.Prefix+"-"), ".sql")
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, n)
+	}
+	slices.Sort(numbers)
+	return numbers, nil
+}
+
+// Applies versioning.sql if needed and all missing patches
+// of all components in dependency order.
+// versioning.sql is taken from the first component providing it.
+func (m *Migrator) Migrate() error {
+	components, err := m.orderedComponents()
+	if err != nil {
+		return err
+	}
+	applied, err := CheckVersioning(m.DB)
+	if err != nil {
+		m.logger().Warn("unable to check versioning", "error", err)
+	}
+	if !applied {
+		versioningFile := ""
+		for _, c := range components {
+			f := filepath.Join(c.DataHome, "sql", "versioning.sql")
+			if _, err := os.Stat(f); err == nil {
+				versioningFile = f
+				break
+			}
+		}
+		if versioningFile == "" {
+			return fmt.Errorf("no versioning.sql found")
+		}
+		m.logger().Info("applying versioning", "file", versioningFile)
+		err := runSQL(m.DB, versioningFile, m.DBName)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range components {
+		err := m.migrateComponent(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) migrateComponent(c DBComponent) error {
+	numbers, err := c.patchNumbers()
+	if err != nil {
+		return err
+	}
+	loadSuffix := filepath.Join(c.DataHome, "sql")
+	for _, n := range numbers {
+		patchName := fmt.Sprintf("%s-%04d", c.Prefix, n)
+		applied, err := CheckPatch(m.DB, patchName)
+		if err != nil {
+			return err
+		}
+		if applied {
+			m.logger().Debug("patch already applied", "patch", patchName)
+			continue
+		}
+		patchFile := fmt.Sprintf("%s.sql", filepath.Join(loadSuffix, patchName))
+		if _, err := os.Stat(patchFile); err != nil {
+			m.logger().Debug("patch not found, skipping", "patch", patchName, "file", patchFile)
+			continue
+		}
+		m.logger().Info("applying patch", "patch", patchName, "file", patchFile)
+		err = runSQL(m.DB, patchFile, m.DBName)
+		if err != nil {
+			return err
+		}
+	}
+	m.logger().Info("component up-to-date", "component", c.Prefix)
+	return nil
+}
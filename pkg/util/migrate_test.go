@@ -0,0 +1,77 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMigratorOrder(t *testing.T) {
+	m := Migrator{
+		Components: []DBComponent{
+			{Prefix: "shopext", DependsOn: []string{"merchant"}},
+			{Prefix: "merchant"},
+			{Prefix: "other"},
+		},
+	}
+	ordered, err := m.orderedComponents()
+	if err != nil {
+		t.Fatalf("Failed ordering components: %v", err)
+	}
+	var prefixes []string
+	for _, c := range ordered {
+		prefixes = append(prefixes, c.Prefix)
+	}
+	if !slices.Equal(prefixes, []string{"merchant", "other", "shopext"}) {
+		t.Errorf("Unexpected order %v", prefixes)
+	}
+	m.Components[1].DependsOn = []string{"shopext"}
+	_, err = m.orderedComponents()
+	if nil == err {
+		t.Errorf("Dependency cycle not detected")
+	}
+}
+
+func TestPatchDiscovery(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sql"), 0755)
+	for _, f := range []string{"shopext-0001.sql", "shopext-0003.sql", "shopext-0003-down.sql", "versioning.sql"} {
+		os.WriteFile(filepath.Join(dir, "sql", f), []byte{}, 0644)
+	}
+	c := DBComponent{Prefix: "shopext", DataHome: dir}
+	numbers, err := c.patchNumbers()
+	if err != nil {
+		t.Fatalf("Failed discovering patches: %v", err)
+	}
+	if !slices.Equal(numbers, []int{1, 3}) {
+		t.Errorf("Unexpected patches %v", numbers)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, p, ran := openTestPatchDB(t, "merchant-0001")
+	p.versioned = false
+	merchantDir := writeSQLFiles(t, "versioning.sql", "merchant-0001.sql", "merchant-0002.sql")
+	shopextDir := writeSQLFiles(t, "shopext-0001.sql", "shopext-0003.sql")
+	m := Migrator{
+		DB:     db,
+		DBName: "test",
+		Components: []DBComponent{
+			{Prefix: "shopext", DataHome: shopextDir, DependsOn: []string{"merchant"}, MaxPatch: 2},
+			{Prefix: "merchant", DataHome: merchantDir},
+		},
+	}
+	err := m.Migrate()
+	if err != nil {
+		t.Fatalf("Failed migrating: %v", err)
+	}
+	if !slices.Equal(*ran, []string{"versioning.sql", "merchant-0002.sql", "shopext-0001.sql"}) {
+		t.Errorf("Unexpected files %v", *ran)
+	}
+	*ran = nil
+	err = m.Migrate()
+	if err != nil || len(*ran) != 0 {
+		t.Errorf("Unexpected second migration %v: %v", *ran, err)
+	}
+}
@@ -0,0 +1,120 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// A payto URI (RFC 8905): payto://<target type>/<target path>[?<params>]
+type PaytoUri struct {
+	// The target type, e.g. "iban" or "x-taler-bank"
+	TargetType string
+
+	// The target path, e.g. the (BIC and) IBAN
+	TargetPath string
+
+	// The query parameters, e.g. "receiver-name"
+	Params url.Values
+}
+
+// IBANs must match this regular expression (after removing spaces)
+var rexIban = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{1,30}$`)
+
+// Check the format and checksum of an IBAN
+func ValidateIban(iban string) error {
+	iban = strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+	if !rexIban.MatchString(iban) {
+		return fmt.Errorf("invalid IBAN format: %s", iban)
+	}
+	// Move country code and check digits to the end, letters become 10..35
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			fmt.Fprintf(&digits, "%d", c-'A'+10)
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("invalid IBAN checksum: %s", iban)
+	}
+	return nil
+}
+
+// Parses and validates a payto URI.
+// For the "iban" target type the IBAN checksum is checked.
+func ParsePaytoUri(s string) (*PaytoUri, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(u.Scheme, "payto") {
+		return nil, fmt.Errorf("not a payto URI: %s", s)
+	}
+	if u.Host == "" {
+		return nil, errors.New("payto URI lacks target type")
+	}
+	p := PaytoUri{
+		TargetType: strings.ToLower(u.Host),
+		TargetPath: strings.TrimPrefix(u.Path, "/"),
+		Params:     u.Query(),
+	}
+	if p.TargetPath == "" {
+		return nil, errors.New("payto URI lacks target path")
+	}
+	if p.TargetType == "iban" {
+		// The path is either <IBAN> or <BIC>/<IBAN>
+		parts := strings.Split(p.TargetPath, "/")
+		if len(parts) > 2 {
+			return nil, fmt.Errorf("invalid iban payto path: %s", p.TargetPath)
+		}
+		err := ValidateIban(parts[len(parts)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// Returns the IBAN of an "iban" payto URI, or the empty string
+func (p *PaytoUri) Iban() string {
+	if p.TargetType != "iban" {
+		return ""
+	}
+	parts := strings.Split(p.TargetPath, "/")
+	return parts[len(parts)-1]
+}
+
+// Returns the payto URI as string
+func (p *PaytoUri) String() string {
+	u := url.URL{
+		Scheme:   "payto",
+		Host:     p.TargetType,
+		Path:     "/" + p.TargetPath,
+		RawQuery: p.Params.Encode(),
+	}
+	return u.String()
+}
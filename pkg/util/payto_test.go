@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestParsePaytoUri(t *testing.T) {
+	p, err := ParsePaytoUri("payto://iban/DE89370400440532013000?receiver-name=Shop%20GmbH")
+	if err != nil {
+		t.Fatalf("Failed parsing payto URI: %v", err)
+	}
+	if p.Iban() != "DE89370400440532013000" || p.Params.Get("receiver-name") != "Shop GmbH" {
+		t.Errorf("Unexpected payto URI %v", p)
+	}
+	_, err = ParsePaytoUri("payto://iban/COBADEFFXXX/DE89370400440532013000")
+	if err != nil {
+		t.Errorf("Failed parsing payto URI with BIC: %v", err)
+	}
+	_, err = ParsePaytoUri("payto://x-taler-bank/bank.example.com/shop")
+	if err != nil {
+		t.Errorf("Failed parsing x-taler-bank payto URI: %v", err)
+	}
+	for _, s := range []string{
+		"payto://iban/DE89370400440532013001",
+		"payto://iban/",
+		"https://example.com/",
+		"payto:///foo",
+	} {
+		_, err = ParsePaytoUri(s)
+		if nil == err {
+			t.Errorf("Invalid payto URI %s accepted", s)
+		}
+	}
+}
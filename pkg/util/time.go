@@ -0,0 +1,139 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// The GNU Taler Timestamp object: {"t_s": <seconds>} or {"t_s": "never"}
+type Timestamp struct {
+	// Seconds since the epoch, ignored if Never is set
+	Seconds uint64
+
+	// The timestamp is "never"
+	Never bool
+}
+
+// Create a new timestamp from t
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{
+		Seconds: uint64(t.Unix()),
+	}
+}
+
+// Returns the timestamp as time.Time. "never" is the maximum time.
+func (t Timestamp) Time() time.Time {
+	if t.Never {
+		return time.Unix(math.MaxInt64/2, 0)
+	}
+	return time.Unix(int64(t.Seconds), 0)
+}
+
+type jsonTimestamp struct {
+	Ts json.RawMessage `json:"t_s"`
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.Never {
+		return []byte(`{"t_s":"never"}`), nil
+	}
+	return []byte(fmt.Sprintf(`{"t_s":%d}`, t.Seconds)), nil
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var j jsonTimestamp
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return err
+	}
+	if string(j.Ts) == `"never"` {
+		*t = Timestamp{Never: true}
+		return nil
+	}
+	var s uint64
+	err = json.Unmarshal(j.Ts, &s)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s", data)
+	}
+	*t = Timestamp{Seconds: s}
+	return nil
+}
+
+// The GNU Taler RelativeTime object: {"d_us": <microseconds>} or
+// {"d_us": "forever"}
+type RelativeTime struct {
+	// Microseconds, ignored if Forever is set
+	Microseconds uint64
+
+	// The relative time is "forever"
+	Forever bool
+}
+
+// Create a new relative time from d
+func NewRelativeTime(d time.Duration) RelativeTime {
+	if d == math.MaxInt64 {
+		return RelativeTime{Forever: true}
+	}
+	return RelativeTime{
+		Microseconds: uint64(d.Microseconds()),
+	}
+}
+
+// Returns the relative time as time.Duration.
+// "forever" and overflowing values are the maximum duration.
+func (r RelativeTime) Duration() time.Duration {
+	if r.Forever || r.Microseconds > math.MaxInt64/uint64(time.Microsecond) {
+		return math.MaxInt64
+	}
+	return time.Duration(r.Microseconds) * time.Microsecond
+}
+
+type jsonRelativeTime struct {
+	Dus json.RawMessage `json:"d_us"`
+}
+
+func (r RelativeTime) MarshalJSON() ([]byte, error) {
+	if r.Forever {
+		return []byte(`{"d_us":"forever"}`), nil
+	}
+	return []byte(fmt.Sprintf(`{"d_us":%d}`, r.Microseconds)), nil
+}
+
+func (r *RelativeTime) UnmarshalJSON(data []byte) error {
+	var j jsonRelativeTime
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return err
+	}
+	if string(j.Dus) == `"forever"` {
+		*r = RelativeTime{Forever: true}
+		return nil
+	}
+	var us uint64
+	err = json.Unmarshal(j.Dus, &us)
+	if err != nil {
+		return fmt.Errorf("invalid relative time %s", data)
+	}
+	*r = RelativeTime{Microseconds: us}
+	return nil
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampJSON(t *testing.T) {
+	var ts Timestamp
+	err := json.Unmarshal([]byte(`{"t_s": 1700000000}`), &ts)
+	if err != nil || ts.Seconds != 1700000000 || ts.Never {
+		t.Errorf("Failed parsing timestamp: %v", err)
+	}
+	err = json.Unmarshal([]byte(`{"t_s": "never"}`), &ts)
+	if err != nil || !ts.Never {
+		t.Errorf("Failed parsing never: %v", err)
+	}
+	b, _ := json.Marshal(NewTimestamp(time.Unix(42, 0)))
+	if string(b) != `{"t_s":42}` {
+		t.Errorf("Unexpected JSON %s", b)
+	}
+}
+
+func TestRelativeTimeJSON(t *testing.T) {
+	var rt RelativeTime
+	err := json.Unmarshal([]byte(`{"d_us": 5000000}`), &rt)
+	if err != nil || rt.Duration() != 5*time.Second {
+		t.Errorf("Failed parsing relative time: %v", err)
+	}
+	err = json.Unmarshal([]byte(`{"d_us": "forever"}`), &rt)
+	if err != nil || !rt.Forever {
+		t.Errorf("Failed parsing forever: %v", err)
+	}
+	b, _ := json.Marshal(NewRelativeTime(time.Hour))
+	if string(b) != `{"d_us":3600000000}` {
+		t.Errorf("Unexpected JSON %s", b)
+	}
+	err = json.Unmarshal([]byte(`{"d_us": "soon"}`), &rt)
+	if nil == err {
+		t.Errorf("Invalid relative time accepted")
+	}
+}
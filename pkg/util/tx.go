@@ -0,0 +1,153 @@
+// This file is part of taler-go, the Taler Go implementation.
+// Copyright (C) 2026 Martin Schanzenbach
+//
+// Taler Go is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// Taler Go is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+
+package util
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLSTATE reported by Postgres if a serializable transaction
+// could not be serialized
+const SQLStateSerializationFailure = "40001"
+
+// SQLSTATE reported by Postgres if a deadlock was detected
+const SQLStateDeadlockDetected = "40P01"
+
+// Errors of lib/pq (*pq.Error) and pgx (*pgconn.PgError) both
+// expose the SQLSTATE through this method.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// Returns the SQLSTATE of the error (or any error it wraps),
+// or the empty string if it does not carry one.
+func SQLState(err error) string {
+	var e sqlStateError
+	if errors.As(err, &e) {
+		return e.SQLState()
+	}
+	return ""
+}
+
+// Check if the transaction that caused this error should be retried,
+// i.e. if it failed due to a serialization failure or a deadlock.
+func IsRetryableTxError(err error) bool {
+	switch SQLState(err) {
+	case SQLStateSerializationFailure, SQLStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// Options for RunTx
+type TxOptions struct {
+	// The isolation level. Defaults to sql.LevelSerializable.
+	Isolation sql.IsolationLevel
+
+	// Run the transaction read-only
+	ReadOnly bool
+
+	// How often to retry a transaction before giving up.
+	// Defaults to DefaultTxMaxRetries.
+	MaxRetries int
+
+	// Delay before the first retry, doubled on every further retry.
+	// Defaults to DefaultTxBackoff.
+	Backoff time.Duration
+
+	// Upper bound for the delay between retries.
+	// Defaults to DefaultTxMaxBackoff.
+	MaxBackoff time.Duration
+}
+
+// Default number of retries for RunTx
+const DefaultTxMaxRetries = 10
+
+// Default initial retry delay for RunTx
+const DefaultTxBackoff = 5 * time.Millisecond
+
+// Default maximum retry delay for RunTx
+const DefaultTxMaxBackoff = time.Second
+
+// Returned (wrapped) by RunTx if the transaction still failed
+// after the maximum number of retries
+var ErrTxRetriesExhausted = errors.New("transaction retries exhausted")
+
+// Runs fn inside a transaction and commits it.
+// If fn or the commit fail with a serialization failure or deadlock,
+// the transaction is rolled back and retried with exponential backoff.
+// Any other error from fn rolls the transaction back and is returned as is.
+// Returns the number of retries that were needed.
+func RunTx(ctx context.Context, db *sql.DB, opts TxOptions, fn func(tx *sql.Tx) error) (int, error) {
+	isolation := opts.Isolation
+	if isolation == sql.LevelDefault {
+		isolation = sql.LevelSerializable
+	}
+	maxRetries := opts.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultTxMaxRetries
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = DefaultTxBackoff
+	}
+	maxBackoff := opts.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultTxMaxBackoff
+	}
+	txOpts := &sql.TxOptions{
+		Isolation: isolation,
+		ReadOnly:  opts.ReadOnly,
+	}
+	for retries := 0; ; retries++ {
+		err := runTxOnce(ctx, db, txOpts, fn)
+		if err == nil {
+			return retries, nil
+		}
+		if !IsRetryableTxError(err) {
+			return retries, err
+		}
+		if retries >= maxRetries {
+			return retries, fmt.Errorf("%w after %d retries: %w", ErrTxRetriesExhausted, retries, err)
+		}
+		select {
+		case <-ctx.Done():
+			return retries, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+func runTxOnce(ctx context.Context, db *sql.DB, txOpts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, txOpts)
+	if err != nil {
+		return err
+	}
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
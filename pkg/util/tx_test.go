@@ -0,0 +1,66 @@
+package util
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/schanzen/taler-go/internal/sqltest"
+)
+
+type testPgError struct {
+	code string
+}
+
+func (e *testPgError) Error() string {
+	return "pq: " + e.code
+}
+
+func (e *testPgError) SQLState() string {
+	return e.code
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	if !IsRetryableTxError(&testPgError{SQLStateSerializationFailure}) {
+		t.Errorf("Serialization failure not retryable")
+	}
+	if !IsRetryableTxError(fmt.Errorf("wrapped: %w", &testPgError{SQLStateDeadlockDetected})) {
+		t.Errorf("Wrapped deadlock not retryable")
+	}
+	if IsRetryableTxError(&testPgError{"23505"}) {
+		t.Errorf("Unique violation retryable")
+	}
+	if IsRetryableTxError(errors.New("foo")) {
+		t.Errorf("Plain error retryable")
+	}
+}
+
+func TestRunTx(t *testing.T) {
+	db := sqltest.Open(t, &sqltest.DB{})
+	opts := TxOptions{MaxRetries: 3, Backoff: 1}
+	calls := 0
+	retries, err := RunTx(context.Background(), db, opts, func(tx *sql.Tx) error {
+		calls++
+		if calls < 3 {
+			return &testPgError{SQLStateSerializationFailure}
+		}
+		return nil
+	})
+	if err != nil || retries != 2 {
+		t.Errorf("Expected success after 2 retries, got %d: %v", retries, err)
+	}
+	retries, err = RunTx(context.Background(), db, opts, func(tx *sql.Tx) error {
+		return &testPgError{SQLStateDeadlockDetected}
+	})
+	if !errors.Is(err, ErrTxRetriesExhausted) || retries != 3 {
+		t.Errorf("Expected exhausted retries, got %d: %v", retries, err)
+	}
+	retries, err = RunTx(context.Background(), db, opts, func(tx *sql.Tx) error {
+		return errors.New("foo")
+	})
+	if err == nil || retries != 0 {
+		t.Errorf("Expected immediate failure, got %d: %v", retries, err)
+	}
+}